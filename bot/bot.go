@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Tipos de eventos que reciben los manejadores
+const (
+	EventMessage = "message" // Mensaje en un canal, Text tiene el contenido
+	EventJoin    = "join"    // Un usuario entro a un canal
+	EventLeave   = "leave"   // Un usuario salio de un canal o se desconecto
+	EventTick    = "tick"    // Tarea periodica de Every, no tiene canal
+)
+
+// Errores del bot
+var ErrNoChannel = errors.New("event has no channel to reply to")
+
+// Estructura para un evento que recibe un manejador
+type Event struct {
+	Type     string
+	Channel  string
+	Nickname string // Autor del mensaje o usuario que entro o salio
+	Text     string
+	Date     time.Time
+	Matches  []string // Coincidencias del patron del manejador, la primera es el texto completo
+
+	ctx       context.Context
+	transport Transport
+}
+
+/* Funcion
+ * Nombre: Reply
+ * Descripcion: Responde con un mensaje en el canal del evento */
+func (e *Event) Reply(text string) error {
+
+	if e.Channel == "" {
+		return ErrNoChannel
+	}
+	return e.Send(e.Channel, text)
+}
+
+/* Funcion
+ * Nombre: Send
+ * Descripcion: Envia un mensaje a un canal */
+func (e *Event) Send(channel string, text string) error {
+
+	return e.transport.Send(e.ctx, channel, text)
+}
+
+/* Funcion
+ * Nombre: Context
+ * Descripcion: Retorna el contexto de Run, se cancela al detener el bot */
+func (e *Event) Context() context.Context {
+
+	return e.ctx
+}
+
+// Manejador de eventos del bot
+type Handler func(event *Event)
+
+// Transporte con el que el bot recibe los eventos y responde, en el mismo proceso del servidor o por TCP
+type Transport interface {
+
+	// Nombre con el que el bot publica sus mensajes, los mensajes de este autor se ignoran
+	Name() string
+
+	// Empieza a escuchar los canales, o todos si no se indican y el transporte lo permite. El canal de
+	// eventos se cierra al cancelar el contexto
+	Listen(ctx context.Context, channels []string) (<-chan Event, error)
+
+	// Publica un mensaje en un canal
+	Send(ctx context.Context, channel string, text string) error
+}
+
+// Estructura para un manejador de mensajes con su patron
+type hear struct {
+	pattern *regexp.Regexp
+	handler Handler
+}
+
+// Estructura para una tarea periodica
+type task struct {
+	interval time.Duration
+	handler  Handler
+}
+
+// Estructura para un bot, se configura con sus manejadores antes de Run
+type Bot struct {
+	Channels []string       // Canales que escucha el bot
+	Logger   *models.Logger // Registro de los manejadores que fallan, nil para descartarlos
+
+	hears    []hear
+	joins    []Handler
+	leaves   []Handler
+	tasks    []task
+	commands map[string]string // Descripcion de cada comando, para la ayuda
+}
+
+/* Funcion
+ * Nombre: New
+ * Descripcion: Crea un bot que escucha los canales dados */
+func New(channels ...string) *Bot {
+
+	return &Bot{Channels: channels, commands: make(map[string]string)}
+}
+
+/* Funcion
+ * Nombre: Hear
+ * Descripcion: Registra un manejador para los mensajes que coinciden con una expresion regular. Si coinciden
+ * varios manejadores se ejecutan todos en el orden en que se registraron. Entra en panico si el patron no es valido */
+func (b *Bot) Hear(pattern string, handler Handler) {
+
+	b.hears = append(b.hears, hear{regexp.MustCompile(pattern), handler})
+}
+
+/* Funcion
+ * Nombre: Command
+ * Descripcion: Registra un comando !nombre, Matches[1] tiene los argumentos escritos despues del nombre
+ * @description: texto del comando en la ayuda */
+func (b *Bot) Command(name string, description string, handler Handler) {
+
+	b.commands[name] = description
+	b.Hear(`^!`+regexp.QuoteMeta(name)+`(?:\s+(.*))?$`, handler)
+}
+
+/* Funcion
+ * Nombre: Help
+ * Descripcion: Retorna la ayuda de los comandos registrados, ordenada por su nombre */
+func (b *Bot) Help() []string {
+
+	help := make([]string, 0, len(b.commands))
+
+	for name, description := range b.commands {
+		help = append(help, "!"+name+" - "+description)
+	}
+	sort.Strings(help)
+	return help
+}
+
+/* Funcion
+ * Nombre: OnJoin
+ * Descripcion: Registra un manejador para los usuarios que entran a los canales del bot */
+func (b *Bot) OnJoin(handler Handler) {
+
+	b.joins = append(b.joins, handler)
+}
+
+/* Funcion
+ * Nombre: OnLeave
+ * Descripcion: Registra un manejador para los usuarios que salen de los canales del bot */
+func (b *Bot) OnLeave(handler Handler) {
+
+	b.leaves = append(b.leaves, handler)
+}
+
+/* Funcion
+ * Nombre: Every
+ * Descripcion: Registra una tarea periodica, como un recordatorio que se envia con Event.Send */
+func (b *Bot) Every(interval time.Duration, handler Handler) {
+
+	b.tasks = append(b.tasks, task{interval, handler})
+}
+
+/* Funcion
+ * Nombre: Run
+ * Descripcion: Ejecuta el bot hasta cancelar el contexto. Los manejadores se ejecutan de a uno, en la rutina de Run,
+ * asi que no necesitan sincronizarse entre ellos pero no deben bloquearse mucho tiempo
+ * return: @error: error al empezar a escuchar, o el del contexto al detenerse */
+func (b *Bot) Run(ctx context.Context, transport Transport) error {
+
+	events, err := transport.Listen(ctx, b.Channels)
+
+	if err != nil {
+		return err
+	}
+
+	ticks := make(chan Handler)
+
+	for _, t := range b.tasks {
+		go func(t task) {
+			ticker := time.NewTicker(t.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					select {
+					case ticks <- t.handler:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(t)
+	}
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case handler := <-ticks:
+			b.call(handler, &Event{Type: EventTick, Date: time.Now(), ctx: ctx, transport: transport})
+
+		case event, ok := <-events:
+			if !ok {
+				return ctx.Err()
+			}
+			event.ctx, event.transport = ctx, transport
+			b.dispatch(&event)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: dispatch
+ * Descripcion: Ejecuta los manejadores que corresponden a un evento */
+func (b *Bot) dispatch(event *Event) {
+
+	if event.Nickname == event.transport.Name() { // Ignoramos los mensajes y las entradas del propio bot
+		return
+	}
+
+	switch event.Type {
+
+	case EventMessage:
+		for _, h := range b.hears {
+			if matches := h.pattern.FindStringSubmatch(event.Text); matches != nil {
+				matched := *event // Cada manejador recibe sus propias coincidencias
+				matched.Matches = matches
+				b.call(h.handler, &matched)
+			}
+		}
+
+	case EventJoin:
+		for _, handler := range b.joins {
+			b.call(handler, event)
+		}
+
+	case EventLeave:
+		for _, handler := range b.leaves {
+			b.call(handler, event)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: call
+ * Descripcion: Ejecuta un manejador, un panico se registra sin detener el bot ni el servidor que lo ejecuta */
+func (b *Bot) call(handler Handler, event *Event) {
+
+	defer func() {
+		if r := recover(); r != nil && b.Logger != nil {
+			b.Logger.Error("bot handler panic", "component", "bot", "event", event.Type, "channel", event.Channel, "panic", fmt.Sprint(r))
+		}
+	}()
+
+	handler(event)
+}
+
+/* Funcion
+ * Nombre: watched
+ * Descripcion: Indica si un canal esta en la lista, los nombres de los canales no distinguen mayusculas. Una lista
+ * vacia incluye todos los canales */
+func watched(channels []string, channel string) bool {
+
+	if len(channels) == 0 {
+		return true
+	}
+
+	for _, name := range channels {
+		if strings.EqualFold(name, channel) {
+			return true
+		}
+	}
+	return false
+}
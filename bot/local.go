@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Eventos del servidor que se guardan mientras el bot ejecuta un manejador, los siguientes se pierden
+const localBuffer = 256
+
+// Transporte en el mismo proceso del servidor: recibe los eventos del servidor y publica con PostMessage,
+// sin una conexion TCP ni ser miembro de los canales. Publica como sys-[name], un nombre que ningun cliente puede usar
+type local struct {
+	server *models.Server
+	name   string
+}
+
+/* Funcion
+ * Nombre: Local
+ * Descripcion: Crea el transporte para ejecutar un bot en el mismo proceso del servidor
+ * @name: nombre del bot, con las mismas reglas que un nombre registrado con REG */
+func Local(server *models.Server, name string) Transport {
+
+	return &local{server: server, name: name}
+}
+
+/* Funcion
+ * Nombre: Name
+ * Descripcion: Retorna el autor de los mensajes del bot, con el prefijo de los procesos del servidor */
+func (l *local) Name() string {
+
+	return models.SystemPrefix + l.name
+}
+
+/* Funcion
+ * Nombre: Listen
+ * Descripcion: Traduce los eventos del servidor de los canales escuchados, todos si no se indican */
+func (l *local) Listen(ctx context.Context, channels []string) (<-chan Event, error) {
+
+	serverEvents, cancel := l.server.Subscribe(localBuffer)
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+		defer cancel()
+
+		for {
+			select {
+
+			case <-ctx.Done():
+				return
+
+			case serverEvent := <-serverEvents:
+				event, ok := localEvent(serverEvent)
+
+				if !ok || !watched(channels, event.Channel) {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+/* Funcion
+ * Nombre: Send
+ * Descripcion: Publica un mensaje en un canal con el nombre del bot */
+func (l *local) Send(ctx context.Context, channel string, text string) error {
+
+	_, err := l.server.PostMessage(channel, l.Name(), []byte(text), nil)
+	return err
+}
+
+/* Funcion
+ * Nombre: localEvent
+ * Descripcion: Convierte un evento del servidor en un evento del bot
+ * return: @bool: false si el evento no le interesa a los bots */
+func localEvent(serverEvent models.Event) (Event, bool) {
+
+	switch serverEvent.Type {
+
+	case models.EventMessage:
+		message := serverEvent.Message
+		return Event{Type: EventMessage, Channel: serverEvent.Channel.Name, Nickname: message.Author, Text: message.Content, Date: message.Date}, true
+
+	case models.EventMemberJoined, models.EventMemberLeft:
+		kind := EventJoin
+		if serverEvent.Type == models.EventMemberLeft {
+			kind = EventLeave
+		}
+
+		nickname := serverEvent.Client.Nickname
+		if nickname == "" { // Igual que en el protocolo, un cliente sin registrar se identifica por su direccion
+			nickname = serverEvent.Client.Address
+		}
+		return Event{Type: kind, Channel: serverEvent.Channel.Name, Nickname: nickname, Date: serverEvent.Time}, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pipeduque/go-server/client"
+)
+
+// Eventos del cliente que se guardan mientras el bot ejecuta un manejador, los siguientes se pierden
+const remoteBuffer = 256
+
+// Errores del transporte por TCP
+var (
+	ErrNoChannels    = errors.New("a remote bot needs at least one channel")
+	ErrNotRegistered = errors.New("a remote bot needs a registered nickname")
+)
+
+// Transporte por TCP con el cliente del protocolo, el bot entra a sus canales como cualquier cliente
+type remote struct {
+	client *client.Client
+}
+
+/* Funcion
+ * Nombre: Remote
+ * Descripcion: Crea el transporte para ejecutar un bot en otro proceso, con un cliente ya conectado y registrado.
+ * El nombre del bot es el registrado en el cliente */
+func Remote(c *client.Client) Transport {
+
+	return &remote{client: c}
+}
+
+/* Funcion
+ * Nombre: Name
+ * Descripcion: Retorna el nombre registrado del cliente, el mismo en cada reconexion */
+func (r *remote) Name() string {
+
+	return r.client.Nickname()
+}
+
+/* Funcion
+ * Nombre: Listen
+ * Descripcion: Entra a los canales y traduce los eventos del cliente. El servidor envia a los miembros cada mensaje
+ * nuevo de sus canales, incluidas las respuestas de los hilos, asi que solo llegan los mensajes posteriores */
+func (r *remote) Listen(ctx context.Context, channels []string) (<-chan Event, error) {
+
+	if len(channels) == 0 {
+		return nil, ErrNoChannels
+	}
+
+	if r.Name() == "" { // Sin nombre registrado el autor de los mensajes del bot cambiaria en cada reconexion
+		return nil, ErrNotRegistered
+	}
+
+	clientEvents, unsubscribe := r.client.Subscribe(remoteBuffer)
+
+	for _, channel := range channels {
+		if err := r.client.Join(ctx, channel, ""); err != nil && !alreadyMember(err) {
+			unsubscribe()
+			return nil, err
+		}
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+
+		for {
+			select {
+
+			case <-ctx.Done():
+				return
+
+			case clientEvent := <-clientEvents:
+				event, ok := remoteEvent(clientEvent)
+
+				if !ok || !watched(channels, event.Channel) {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+/* Funcion
+ * Nombre: Send
+ * Descripcion: Envia un mensaje a un canal, el bot debe ser miembro */
+func (r *remote) Send(ctx context.Context, channel string, text string) error {
+
+	return r.client.Send(ctx, channel, text, nil)
+}
+
+/* Funcion
+ * Nombre: remoteEvent
+ * Descripcion: Convierte un evento del cliente en un evento del bot
+ * return: @bool: false si el evento no le interesa a los bots */
+func remoteEvent(clientEvent client.Event) (Event, bool) {
+
+	switch clientEvent.Type {
+
+	case client.EventMessage:
+		message := clientEvent.Message
+		return Event{Type: EventMessage, Channel: clientEvent.Channel, Nickname: message.Author, Text: message.Content, Date: message.Date}, true
+
+	case client.EventJoin, client.EventLeave:
+		kind := map[string]string{client.EventJoin: EventJoin, client.EventLeave: EventLeave}[clientEvent.Type]
+		return Event{Type: kind, Channel: clientEvent.Channel, Nickname: clientEvent.Nickname, Date: time.Now()}, true
+	}
+	return Event{}, false
+}
+
+/* Funcion
+ * Nombre: alreadyMember
+ * Descripcion: Indica si el servidor rechazo la entrada porque el cliente ya era miembro del canal */
+func alreadyMember(err error) bool {
+
+	var serverErr *client.ServerError
+	return errors.As(err, &serverErr) && strings.HasPrefix(serverErr.Message, "already a member")
+}
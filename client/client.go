@@ -0,0 +1,506 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Errores del cliente
+var (
+	ErrClosed       = errors.New("client closed")
+	ErrNotConnected = errors.New("not connected")
+	ErrDisconnected = errors.New("connection lost before the response")
+	ErrTimeout      = errors.New("server did not confirm the request")
+)
+
+// Error respondido por el servidor a una solicitud, con el texto de su linea ERROR
+type ServerError struct {
+	Message string
+}
+
+/* Funcion
+ * Nombre: Error
+ * Descripcion: Retorna el texto del error del servidor */
+func (e *ServerError) Error() string {
+
+	return e.Message
+}
+
+// Valores por defecto de las opciones
+const (
+	defaultDialTimeout    = 5 * time.Second
+	defaultRequestTimeout = 30 * time.Second
+	defaultRetryDelay     = time.Second
+	defaultMaxRetryDelay  = 30 * time.Second
+)
+
+// Estructura con las opciones de la conexion, los valores en cero usan los valores por defecto
+type Options struct {
+	Nickname         string        // Nombre a registrar al conectarse y en cada reconexion
+	Password         string        // Contraseña del nombre, vacia para registrarse como invitado
+	DialTimeout      time.Duration // Tiempo maximo para conectarse al servidor
+	RequestTimeout   time.Duration // Tiempo maximo para que el servidor confirme una solicitud, luego se reconecta
+	RetryDelay       time.Duration // Espera antes del primer intento de reconexion, se duplica en cada fallo
+	MaxRetryDelay    time.Duration // Espera maxima entre intentos de reconexion
+	DisableReconnect bool          // No reconectarse al perder la conexion
+}
+
+// Estructura para una solicitud en curso, recibe las respuestas hasta la confirmacion DONE de su etiqueta
+type request struct {
+	tag   string
+	lines []string      // Respuestas a la solicitud, sin los eventos
+	err   error         // Error respondido por el servidor o de la conexion
+	done  chan struct{} // Se cierra al confirmarse la solicitud o perderse la conexion
+	timer *time.Timer   // Cierra la conexion si el servidor no confirma la solicitud
+}
+
+// Estructura para un cliente del protocolo. Envia una solicitud a la vez con una etiqueta; el servidor responde
+// con el prefijo @[etiqueta], asi las respuestas se asocian a su solicitud y las demas lineas son eventos
+type Client struct {
+	address  string
+	options  Options
+	slot     chan struct{} // Turno para enviar una solicitud, se libera cuando el servidor la confirma
+	mu       sync.Mutex    // Protege los campos siguientes
+	conn     net.Conn      // Conexion actual, nil mientras se reconecta
+	pending  *request      // Solicitud en curso
+	tags     uint64        // Ultima etiqueta asignada
+	nickname string        // Nombre registrado, se vuelve a registrar al reconectar
+	password string        // Contraseña del nombre registrado
+	channels map[string]string
+	closed   bool
+	writeMu  sync.Mutex // Serializa las escrituras a la conexion
+	events   events
+	done     chan struct{} // Se cierra al cerrar el cliente
+}
+
+/* Funcion
+ * Nombre: Connect
+ * Descripcion: Se conecta al servidor y registra el nombre de las opciones si tiene. Si luego se pierde la
+ * conexion el cliente se reconecta solo, vuelve a registrar el nombre y a entrar a sus canales
+ * @ctx: contexto de la conexion inicial
+ * @address: direccion TCP del servidor
+ * @options: opciones de la conexion
+ * return: @*Client: cliente conectado
+ *         @error: err si no se pudo conectar o registrar el nombre */
+func Connect(ctx context.Context, address string, options Options) (*Client, error) {
+
+	if options.DialTimeout <= 0 {
+		options.DialTimeout = defaultDialTimeout
+	}
+	if options.RequestTimeout <= 0 {
+		options.RequestTimeout = defaultRequestTimeout
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = defaultRetryDelay
+	}
+	if options.MaxRetryDelay <= 0 {
+		options.MaxRetryDelay = defaultMaxRetryDelay
+	}
+
+	c := &Client{
+		address:  address,
+		options:  options,
+		slot:     make(chan struct{}, 1),
+		channels: make(map[string]string),
+		events:   events{subscribers: make(map[chan Event]bool)},
+		done:     make(chan struct{}),
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+	go c.run(conn)
+
+	if options.Nickname != "" {
+		if err := c.Register(ctx, options.Nickname, options.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+/* Funcion
+ * Nombre: dial
+ * Descripcion: Abre una conexion TCP con el servidor */
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+
+	dialer := net.Dialer{Timeout: c.options.DialTimeout}
+	return dialer.DialContext(ctx, "tcp", c.address)
+}
+
+/* Funcion
+ * Nombre: run
+ * Descripcion: Lee las lineas de la conexion y se reconecta cuando se pierde, hasta que se cierre el cliente */
+func (c *Client) run(conn net.Conn) {
+
+	for {
+		err := c.read(conn)
+		c.disconnected(conn, err)
+
+		if c.options.DisableReconnect {
+			c.Close()
+			return
+		}
+
+		if conn = c.reconnect(); conn == nil { // El cliente se cerro mientras reconectaba
+			return
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: read
+ * Descripcion: Lee las lineas de una conexion, las respuestas van a la solicitud en curso y el resto a los eventos
+ * return: @error: razon por la que termino la conexion */
+func (c *Client) read(conn net.Conn) error {
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		c.route(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+/* Funcion
+ * Nombre: route
+ * Descripcion: Entrega una linea del servidor a la solicitud en curso si tiene su etiqueta, o a los suscriptores
+ * de eventos si no tiene etiqueta
+ * @line: linea sin el salto de linea */
+func (c *Client) route(line string) {
+
+	if strings.HasPrefix(line, "@") { // Respuesta a una solicitud, @[etiqueta] [respuesta]
+		tag, response := split(line[1:])
+		c.respond(tag, response)
+		return
+	}
+
+	kind, args := split(line)
+
+	switch kind {
+
+	case "PING": // Respondemos el latido para que el servidor no nos desconecte
+		c.write(strings.TrimSpace("PONG " + args))
+		return
+
+	case "DONE":
+		c.mu.Lock()
+		pending := c.pending
+		if pending != nil && pending.tag == args {
+			c.pending = nil
+		}
+		c.mu.Unlock()
+
+		if pending != nil && pending.tag == args {
+			c.finish(pending, nil)
+		}
+		return
+	}
+
+	if event, ok := parseEvent(kind, args); ok {
+		c.events.publish(event)
+		return
+	}
+
+	if kind != "ERROR" { // Las demas lineas sin etiqueta no son para este cliente
+		return
+	}
+
+	// Un error sin etiqueta ocurre fuera de una solicitud, por ejemplo al ser expulsado, o cuando el servidor no pudo
+	// leer la solicitud en curso por su tamaño y no la va a confirmar
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	if pending == nil {
+		c.events.publish(Event{Type: EventError, Text: args})
+		return
+	}
+	pending.err = &ServerError{args}
+	c.finish(pending, nil)
+}
+
+/* Funcion
+ * Nombre: respond
+ * Descripcion: Agrega una respuesta con etiqueta a la solicitud en curso, si sigue esperando esa etiqueta
+ * @tag: etiqueta de la respuesta
+ * @response: respuesta sin la etiqueta */
+func (c *Client) respond(tag string, response string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.pending
+
+	if pending == nil || pending.tag != tag { // Respuesta de una solicitud que ya vencio
+		return
+	}
+
+	if kind, args := split(response); kind == "ERROR" {
+		pending.err = &ServerError{args}
+	} else {
+		pending.lines = append(pending.lines, response)
+	}
+}
+
+/* Funcion
+ * Nombre: finish
+ * Descripcion: Termina una solicitud y libera el turno para la siguiente
+ * @err: error de la conexion, nil si el servidor confirmo la solicitud */
+func (c *Client) finish(pending *request, err error) {
+
+	pending.timer.Stop()
+
+	if pending.err == nil { // Conservamos el error que ya respondio el servidor o el vencimiento
+		pending.err = err
+	}
+	close(pending.done)
+	<-c.slot
+}
+
+/* Funcion
+ * Nombre: timeout
+ * Descripcion: Da por perdida la conexion cuando el servidor no confirma una solicitud a tiempo */
+func (c *Client) timeout(conn net.Conn, pending *request) {
+
+	c.mu.Lock()
+	if c.pending == pending {
+		pending.err = ErrTimeout
+	}
+	c.mu.Unlock()
+
+	conn.Close() // La lectura falla la solicitud y reconecta
+}
+
+/* Funcion
+ * Nombre: disconnected
+ * Descripcion: Descarta la conexion perdida, fallando la solicitud en curso */
+func (c *Client) disconnected(conn net.Conn, err error) {
+
+	conn.Close()
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.conn = nil
+	closed := c.closed
+	c.mu.Unlock()
+
+	if pending != nil {
+		c.finish(pending, ErrDisconnected)
+	}
+
+	if !closed {
+		c.events.publish(Event{Type: EventDisconnected, Text: err.Error()})
+	}
+}
+
+/* Funcion
+ * Nombre: reconnect
+ * Descripcion: Intenta conectarse con esperas crecientes y restaura el nombre y los canales del cliente
+ * return: @net.Conn: conexion nueva, nil si el cliente se cerro */
+func (c *Client) reconnect() net.Conn {
+
+	delay := c.options.RetryDelay
+
+	for {
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := c.dial(context.Background())
+
+		if err == nil {
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				conn.Close()
+				return nil
+			}
+			c.conn = conn
+			c.mu.Unlock()
+
+			go c.restore()
+			return conn
+		}
+
+		if delay *= 2; delay > c.options.MaxRetryDelay {
+			delay = c.options.MaxRetryDelay
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: restore
+ * Descripcion: Vuelve a registrar el nombre y a entrar a los canales despues de reconectarse */
+func (c *Client) restore() {
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.RequestTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	nickname, password := c.nickname, c.password
+	channels := make(map[string]string, len(c.channels))
+	for channel, password := range c.channels {
+		channels[channel] = password
+	}
+	c.mu.Unlock()
+
+	if nickname != "" {
+		c.Register(ctx, nickname, password)
+	}
+	for channel, password := range channels {
+		c.Join(ctx, channel, password)
+	}
+
+	c.events.publish(Event{Type: EventConnected})
+}
+
+/* Funcion
+ * Nombre: write
+ * Descripcion: Escribe una linea a la conexion actual */
+func (c *Client) write(line string) error {
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(c.options.RequestTimeout))
+	_, err := conn.Write([]byte(line + "\n"))
+	return err
+}
+
+/* Funcion
+ * Nombre: Do
+ * Descripcion: Envia un comando del protocolo y espera a que el servidor lo confirme. Las solicitudes se envian
+ * de a una; si el contexto se cancela antes de la confirmacion, la siguiente espera a que llegue
+ * @ctx: contexto de la solicitud
+ * @id: comando del protocolo
+ * @args: argumentos del comando, se escapan los separadores
+ * return: @[]string: respuestas del servidor a la solicitud, sin los eventos
+ *         @error: *ServerError si el servidor respondio ERROR, o err de la conexion o del contexto */
+func (c *Client) Do(ctx context.Context, id models.ID, args ...string) ([]string, error) {
+
+	line, err := formatRequest(id, args)
+	if err != nil {
+		return nil, err
+	}
+
+	select { // Esperamos el turno para enviar la solicitud
+	case c.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, ErrClosed
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	if conn == nil {
+		c.mu.Unlock()
+		<-c.slot
+		return nil, ErrNotConnected
+	}
+	c.tags++
+	pending := &request{tag: strconv.FormatUint(c.tags, 36), done: make(chan struct{})}
+	pending.timer = time.AfterFunc(c.options.RequestTimeout, func() { c.timeout(conn, pending) })
+	c.pending = pending
+	c.mu.Unlock()
+
+	if err := c.write("@" + pending.tag + " " + line); err != nil {
+		conn.Close() // La lectura falla la solicitud en curso y reconecta
+		<-pending.done
+		return nil, err
+	}
+
+	select {
+	case <-pending.done:
+		return pending.lines, pending.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+/* Funcion
+ * Nombre: Nickname
+ * Descripcion: Retorna el nombre registrado del cliente, vacio si no se ha registrado */
+func (c *Client) Nickname() string {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.nickname
+}
+
+/* Funcion
+ * Nombre: Name
+ * Descripcion: Retorna el nombre con el que el servidor identifica al cliente en sus respuestas y eventos: el
+ * nombre registrado, o la direccion local de la conexion si no se ha registrado */
+func (c *Client) Name() string {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.nickname != "" || c.conn == nil {
+		return c.nickname
+	}
+	return c.conn.LocalAddr().String()
+}
+
+/* Funcion
+ * Nombre: Close
+ * Descripcion: Cierra la conexion y detiene la reconexion */
+func (c *Client) Close() error {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.mu.Unlock()
+
+	close(c.done)
+
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: split
+ * Descripcion: Separa el primer corte de una linea del resto */
+func split(line string) (string, string) {
+
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		return line[:i], line[i+1:]
+	}
+	return line, ""
+}
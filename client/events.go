@@ -0,0 +1,138 @@
+package client
+
+import (
+	"strconv"
+	"sync"
+)
+
+// Tipos de eventos del cliente
+const (
+	EventConnected    = "connected"    // Se restablecio la conexion, con el nombre y los canales restaurados
+	EventDisconnected = "disconnected" // Se perdio la conexion, Text tiene la razon
+	EventJoin         = "join"         // Un usuario entro a un canal, incluido este cliente
+	EventLeave        = "leave"        // Un usuario salio de un canal
+	EventTopic        = "topic"        // Cambio el tema de un canal, Text tiene el tema
+	EventMode         = "mode"         // Cambiaron los modos de un canal, Text tiene los modos
+	EventInvite       = "invite"       // Un usuario invito a este cliente a un canal
+	EventMessage      = "message"      // Mensaje nuevo en un canal del cliente, incluidas las respuestas de los hilos
+	EventDirect       = "direct"       // Mensaje directo recibido
+	EventEdit         = "edit"         // Se edito un mensaje de un canal, Message tiene su identificador y contenido
+	EventDelete       = "delete"       // Se elimino un mensaje de un canal, Message tiene su identificador
+	EventReply        = "reply"        // Respuesta nueva en un hilo en el que participa este cliente
+	EventReact        = "react"        // Un usuario reacciono a un mensaje de un canal, Text tiene la reaccion
+	EventUnreact      = "unreact"      // Un usuario quito su reaccion a un mensaje de un canal, Text tiene la reaccion
+	EventNotice       = "notice"       // Aviso del servidor
+	EventError        = "error"        // Error del servidor sin solicitud en curso, por ejemplo al ser expulsado
+)
+
+// Estructura para un evento del servidor, solo tiene los datos que corresponden a su tipo
+type Event struct {
+	Type     string
+	Channel  string
+	Nickname string   // Usuario que entro, salio, invito o reacciono
+	Text     string   // Tema, modos, reaccion, aviso o error
+	Message  *Message // Mensaje nuevo, directo o respuesta de un hilo, o mensaje editado, eliminado o con reacciones
+}
+
+// Estructura para los suscriptores a los eventos del cliente
+type events struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]bool
+}
+
+/* Funcion
+ * Nombre: publish
+ * Descripcion: Entrega un evento a todos los suscriptores sin bloquear, un suscriptor lento pierde el evento */
+func (e *events) publish(event Event) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for subscriber := range e.subscribers {
+		select {
+		case subscriber <- event:
+		default: // El suscriptor no alcanza a leer sus eventos
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: Subscribe
+ * Descripcion: Suscribe a los eventos del servidor
+ * @size: eventos que se guardan mientras el suscriptor no los lee, los siguientes se pierden
+ * return: @<-chan Event: canal de eventos
+ *         @func(): cancela la suscripcion */
+func (c *Client) Subscribe(size int) (<-chan Event, func()) {
+
+	subscriber := make(chan Event, size)
+
+	c.events.mu.Lock()
+	c.events.subscribers[subscriber] = true
+	c.events.mu.Unlock()
+
+	var once sync.Once
+
+	return subscriber, func() {
+		once.Do(func() {
+			c.events.mu.Lock()
+			delete(c.events.subscribers, subscriber)
+			c.events.mu.Unlock()
+		})
+	}
+}
+
+/* Funcion
+ * Nombre: parseEvent
+ * Descripcion: Reconoce las lineas del servidor que no son respuestas a una solicitud
+ * @kind: primer corte de la linea
+ * @args: resto de la linea
+ * return: @Event: evento de la linea
+ *         @bool: false si la linea es una respuesta */
+func parseEvent(kind string, args string) (Event, bool) {
+
+	switch kind {
+
+	case "JOIN", "LEAVE", "INVITE":
+		channel, nickname := split(args)
+		types := map[string]string{"JOIN": EventJoin, "LEAVE": EventLeave, "INVITE": EventInvite}
+		return Event{Type: types[kind], Channel: channel, Nickname: nickname}, true
+
+	case "TOPIC", "MODE":
+		channel, text := split(args)
+		types := map[string]string{"TOPIC": EventTopic, "MODE": EventMode}
+		return Event{Type: types[kind], Channel: channel, Text: text}, true
+
+	case "DM":
+		message := parseMessage(args)
+		return Event{Type: EventDirect, Nickname: message.Author, Message: &message}, true
+
+	case "MSG", "REPLY": // MSG [canal] [id_raiz] [entrada], con id_raiz cero si no es una respuesta
+		channel, rest := split(args)
+		parent, entry := split(rest)
+		message := parseEntry(entry)
+		message.Parent, _ = strconv.ParseUint(parent, 10, 64)
+		types := map[string]string{"MSG": EventMessage, "REPLY": EventReply}
+		return Event{Type: types[kind], Channel: channel, Nickname: message.Author, Message: &message}, true
+
+	case "REACT", "UNREACT": // REACT [canal] [id] [reaccion] [usuario]
+		channel, rest := split(args)
+		id, rest := split(rest)
+		reaction, nickname := split(rest)
+		message := Message{}
+		message.ID, _ = strconv.ParseUint(id, 10, 64)
+		types := map[string]string{"REACT": EventReact, "UNREACT": EventUnreact}
+		return Event{Type: types[kind], Channel: channel, Nickname: nickname, Text: reaction, Message: &message}, true
+
+	case "EDIT", "DELETE": // EDIT [canal] [id] [contenido escapado] o DELETE [canal] [id]
+		channel, rest := split(args)
+		id, content := split(rest)
+		message := Message{Content: unescape(content), Deleted: kind == "DELETE"}
+		message.ID, _ = strconv.ParseUint(id, 10, 64)
+		types := map[string]string{"EDIT": EventEdit, "DELETE": EventDelete}
+		return Event{Type: types[kind], Channel: channel, Message: &message}, true
+
+	case "NOTICE":
+		return Event{Type: EventNotice, Text: args}, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,535 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Errores de las solicitudes
+var (
+	ErrChannelExists   = errors.New("channel already exists")
+	ErrInvalidArgument = errors.New("argument contains a line break")
+	ErrUnknownCommand  = errors.New("unknown command")
+)
+
+// Separador de argumentos y formato de las fechas del protocolo
+const (
+	separator  = ";;"
+	dateLayout = "2006-01-02:15:04:05"
+)
+
+// Reemplazos para enviar el separador y el escape dentro de un argumento
+var escaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`)
+
+// Estructura para un canal de LIST_CHN
+type Channel struct {
+	Name        string
+	Topic       string
+	Description string
+	Creator     string
+	Members     int
+	Created     time.Time
+	Activity    time.Time
+}
+
+// Estructura para un mensaje de un canal o una conversacion
+type Message struct {
+	ID        uint64 // Identificador dentro del canal, cero en los mensajes directos
+	Date      time.Time
+	Author    string
+	Content   string
+	File      []byte     // Archivo adjunto, ya decodificado del base64
+	Deleted   bool       // El mensaje del canal fue eliminado, queda sin contenido
+	Parent    uint64     // Mensaje raiz del hilo de una respuesta, en Thread y en los eventos message y reply
+	Replies   int        // Respuestas del hilo de un mensaje del canal
+	Reactions []Reaction // Reacciones de un mensaje del canal, en el orden en que se usaron
+}
+
+// Estructura para la cantidad de clientes que usaron una reaccion en un mensaje
+type Reaction struct {
+	Reaction string
+	Count    int
+}
+
+// Estructura para una conversacion directa de LIST_DM
+type Conversation struct {
+	Nickname string
+	Messages int
+	Date     time.Time // Fecha del ultimo mensaje
+}
+
+/* Funcion
+ * Nombre: Register
+ * Descripcion: Registra el nombre del cliente, se vuelve a registrar en cada reconexion. Con contraseña reclama
+ * el nombre, o demuestra ser su dueño si ya estaba reclamado; sin ella se registra como invitado, sin mensajes directos */
+func (c *Client) Register(ctx context.Context, nickname string, password string) error {
+
+	if _, err := c.Do(ctx, models.REG, nickname, password); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.nickname, c.password = nickname, password
+	c.mu.Unlock()
+	return nil
+}
+
+/* Funcion
+ * Nombre: Create
+ * Descripcion: Crea un canal
+ * return: @error: ErrChannelExists si el canal ya existe */
+func (c *Client) Create(ctx context.Context, channel string) error {
+
+	lines, err := c.Do(ctx, models.CREATE, channel)
+
+	if err == nil && len(lines) > 0 && lines[0] == "FALSE" {
+		return ErrChannelExists
+	}
+	return err
+}
+
+/* Funcion
+ * Nombre: Join
+ * Descripcion: Entra a un canal con su contraseña opcional, se vuelve a entrar en cada reconexion */
+func (c *Client) Join(ctx context.Context, channel string, password string) error {
+
+	if _, err := c.Do(ctx, models.JOIN, channel, password); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.channels[channel] = password
+	c.mu.Unlock()
+	return nil
+}
+
+/* Funcion
+ * Nombre: Leave
+ * Descripcion: Sale de un canal */
+func (c *Client) Leave(ctx context.Context, channel string) error {
+
+	if _, err := c.Do(ctx, models.LEAVE, channel); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.channels, channel)
+	c.mu.Unlock()
+	return nil
+}
+
+/* Funcion
+ * Nombre: Send
+ * Descripcion: Envia un mensaje a un canal
+ * @file: archivo adjunto opcional, se envia en base64 */
+func (c *Client) Send(ctx context.Context, channel string, content string, file []byte) error {
+
+	_, err := c.Do(ctx, models.MSG, channel, content, encodeFile(file))
+	return err
+}
+
+/* Funcion
+ * Nombre: Reply
+ * Descripcion: Responde a un mensaje de un canal, la respuesta se agrega al hilo del mensaje
+ * @file: archivo adjunto opcional, se envia en base64 */
+func (c *Client) Reply(ctx context.Context, channel string, parent uint64, content string, file []byte) error {
+
+	_, err := c.Do(ctx, models.MSG, channel, content, encodeFile(file), strconv.FormatUint(parent, 10))
+	return err
+}
+
+/* Funcion
+ * Nombre: Edit
+ * Descripcion: Cambia el contenido de un mensaje de un canal, solo lo pueden hacer su autor o un operador */
+func (c *Client) Edit(ctx context.Context, channel string, id uint64, content string) error {
+
+	_, err := c.Do(ctx, models.EDIT, channel, strconv.FormatUint(id, 10), content)
+	return err
+}
+
+/* Funcion
+ * Nombre: Delete
+ * Descripcion: Elimina un mensaje de un canal, solo lo pueden hacer su autor o un operador */
+func (c *Client) Delete(ctx context.Context, channel string, id uint64) error {
+
+	_, err := c.Do(ctx, models.DELETE, channel, strconv.FormatUint(id, 10))
+	return err
+}
+
+/* Funcion
+ * Nombre: React
+ * Descripcion: Reacciona a un mensaje de un canal con un emoji o un texto corto sin espacios */
+func (c *Client) React(ctx context.Context, channel string, id uint64, reaction string) error {
+
+	_, err := c.Do(ctx, models.REACT, channel, strconv.FormatUint(id, 10), reaction)
+	return err
+}
+
+/* Funcion
+ * Nombre: Unreact
+ * Descripcion: Quita una reaccion del cliente a un mensaje de un canal */
+func (c *Client) Unreact(ctx context.Context, channel string, id uint64, reaction string) error {
+
+	_, err := c.Do(ctx, models.UNREACT, channel, strconv.FormatUint(id, 10), reaction)
+	return err
+}
+
+/* Funcion
+ * Nombre: Direct
+ * Descripcion: Envia un mensaje directo a un usuario registrado
+ * @file: archivo adjunto opcional, se envia en base64 */
+func (c *Client) Direct(ctx context.Context, nickname string, content string, file []byte) error {
+
+	_, err := c.Do(ctx, models.DM, nickname, content, encodeFile(file))
+	return err
+}
+
+/* Funcion
+ * Nombre: Invite
+ * Descripcion: Invita a un usuario a un canal */
+func (c *Client) Invite(ctx context.Context, channel string, nickname string) error {
+
+	_, err := c.Do(ctx, models.INVITE, channel, nickname)
+	return err
+}
+
+/* Funcion
+ * Nombre: SetMode
+ * Descripcion: Cambia un modo de un canal, como +p, -i o +k con su contraseña */
+func (c *Client) SetMode(ctx context.Context, channel string, mode string, password string) error {
+
+	_, err := c.Do(ctx, models.MODE, channel, mode, password)
+	return err
+}
+
+/* Funcion
+ * Nombre: SetTopic
+ * Descripcion: Cambia el tema de un canal y su descripcion, la descripcion vacia conserva la actual */
+func (c *Client) SetTopic(ctx context.Context, channel string, topic string, description string) error {
+
+	_, err := c.Do(ctx, models.TOPIC, channel, topic, description)
+	return err
+}
+
+/* Funcion
+ * Nombre: Topic
+ * Descripcion: Consulta el tema y la descripcion de un canal */
+func (c *Client) Topic(ctx context.Context, channel string) (string, string, error) {
+
+	lines, err := c.Do(ctx, models.TOPIC, channel)
+
+	if err != nil || len(lines) == 0 {
+		return "", "", err
+	}
+
+	fields := splitEscaped(lines[0], ',') // tema,descripcion escapados
+
+	topic, description := unescape(fields[0]), ""
+	if len(fields) > 1 {
+		description = unescape(fields[1])
+	}
+	return topic, description, nil
+}
+
+/* Funcion
+ * Nombre: ListChannels
+ * Descripcion: Lista los canales visibles para el cliente */
+func (c *Client) ListChannels(ctx context.Context) ([]Channel, error) {
+
+	lines, err := c.Do(ctx, models.LIST_CHN)
+
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]Channel, 0)
+
+	for _, entry := range entries(lines) {
+
+		fields := splitEscaped(entry, ',')
+
+		if len(fields) != 7 { // Manejamos entradas incompletas
+			continue
+		}
+
+		channel := Channel{
+			Created:     parseDate(fields[0]),
+			Name:        fields[1],
+			Topic:       unescape(fields[2]),
+			Description: unescape(fields[3]),
+			Creator:     fields[4],
+			Activity:    parseDate(fields[6]),
+		}
+		channel.Members, _ = strconv.Atoi(fields[5])
+
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+/* Funcion
+ * Nombre: ListMessages
+ * Descripcion: Lista los mensajes de un canal en orden de llegada, incluidos los eliminados y sin las respuestas de
+ * los hilos */
+func (c *Client) ListMessages(ctx context.Context, channel string) ([]Message, error) {
+
+	lines, err := c.Do(ctx, models.LIST_MSG, channel)
+
+	messages := make([]Message, 0)
+
+	for _, entry := range entries(lines) {
+		messages = append(messages, parseEntry(entry))
+	}
+	return messages, err
+}
+
+/* Funcion
+ * Nombre: Thread
+ * Descripcion: Lista el hilo de un mensaje de un canal: el mensaje raiz seguido de sus respuestas
+ * @id: identificador del mensaje raiz o de una de sus respuestas */
+func (c *Client) Thread(ctx context.Context, channel string, id uint64) ([]Message, error) {
+
+	lines, err := c.Do(ctx, models.THREAD, channel, strconv.FormatUint(id, 10))
+
+	messages := make([]Message, 0)
+
+	for i, entry := range entries(lines) {
+		message := parseEntry(entry)
+		if i > 0 {
+			message.Parent = messages[0].ID
+		}
+		messages = append(messages, message)
+	}
+	return messages, err
+}
+
+/* Funcion
+ * Nombre: ListUsers
+ * Descripcion: Lista los usuarios conectados a un canal */
+func (c *Client) ListUsers(ctx context.Context, channel string) ([]string, error) {
+
+	lines, err := c.Do(ctx, models.LIST_USR, channel)
+
+	if err != nil {
+		return nil, err
+	}
+	return entries(lines), nil
+}
+
+/* Funcion
+ * Nombre: ListDirects
+ * Descripcion: Lista las conversaciones directas del cliente, ordenadas por su ultimo mensaje */
+func (c *Client) ListDirects(ctx context.Context) ([]Conversation, error) {
+
+	lines, err := c.Do(ctx, models.LIST_DM)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]Conversation, 0)
+
+	for _, entry := range entries(lines) {
+		fields := splitEscaped(entry, ',')
+		if len(fields) == 3 {
+			count, _ := strconv.Atoi(fields[2])
+			conversations = append(conversations, Conversation{Date: parseDate(fields[0]), Nickname: fields[1], Messages: count})
+		}
+	}
+	return conversations, nil
+}
+
+/* Funcion
+ * Nombre: ListDirectMessages
+ * Descripcion: Lista los mensajes de la conversacion con un usuario */
+func (c *Client) ListDirectMessages(ctx context.Context, nickname string) ([]Message, error) {
+
+	lines, err := c.Do(ctx, models.LIST_DM, nickname)
+	return parseMessages(lines), err
+}
+
+/* Funcion
+ * Nombre: Escape
+ * Descripcion: Escapa el separador y el escape de un argumento del protocolo */
+func Escape(arg string) string {
+
+	return escaper.Replace(arg)
+}
+
+/* Funcion
+ * Nombre: formatRequest
+ * Descripcion: Arma una solicitud COMANDO arg;;arg con los argumentos escapados, sin los opcionales vacios del final */
+func formatRequest(id models.ID, args []string) (string, error) {
+
+	name := id.String()
+
+	if name == "UNKNOWN" {
+		return "", ErrUnknownCommand
+	}
+
+	for len(args) > 0 && args[len(args)-1] == "" {
+		args = args[:len(args)-1]
+	}
+
+	escaped := make([]string, len(args))
+
+	for i, arg := range args {
+		if strings.ContainsAny(arg, "\r\n") { // Un salto de linea terminaria la solicitud
+			return "", ErrInvalidArgument
+		}
+		escaped[i] = Escape(arg)
+	}
+
+	if len(escaped) == 0 {
+		return name, nil
+	}
+	return name + " " + strings.Join(escaped, separator), nil
+}
+
+/* Funcion
+ * Nombre: entries
+ * Descripcion: Separa las entradas de un listado del protocolo, entrada;entrada;, sin resolver sus escapes */
+func entries(lines []string) []string {
+
+	result := make([]string, 0)
+
+	for _, line := range lines {
+		for _, entry := range splitEscaped(line, ';') {
+			if entry != "" {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result
+}
+
+/* Funcion
+ * Nombre: parseMessages
+ * Descripcion: Convierte un listado de mensajes del protocolo */
+func parseMessages(lines []string) []Message {
+
+	messages := make([]Message, 0)
+
+	for _, entry := range entries(lines) {
+		messages = append(messages, parseMessage(entry))
+	}
+	return messages
+}
+
+/* Funcion
+ * Nombre: parseMessage
+ * Descripcion: Convierte un mensaje del protocolo fecha,autor,contenido,archivo, con el contenido escapado */
+func parseMessage(entry string) Message {
+
+	fields := splitEscaped(entry, ',')
+
+	if len(fields) != 4 {
+		return Message{Content: unescape(entry)}
+	}
+
+	message := Message{Date: parseDate(fields[0]), Author: fields[1], Content: unescape(fields[2])}
+
+	if file := fields[3]; file != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(file); err == nil {
+			message.File = decoded
+		} else {
+			message.File = []byte(file)
+		}
+	}
+	return message
+}
+
+/* Funcion
+ * Nombre: parseEntry
+ * Descripcion: Convierte una entrada del historial de un canal id,respuestas,reacciones,fecha,autor,contenido,archivo */
+func parseEntry(entry string) Message {
+
+	fields := splitEscaped(entry, ',')
+
+	if len(fields) != 7 {
+		return parseMessage(entry)
+	}
+
+	message := parseMessage(strings.Join(fields[3:], ","))
+	message.ID, _ = strconv.ParseUint(fields[0], 10, 64)
+	message.Replies, _ = strconv.Atoi(fields[1])
+
+	for _, field := range strings.Fields(fields[2]) { // reaccion:cantidad separadas por espacios
+		if i := strings.LastIndexByte(field, ':'); i > 0 {
+			count, _ := strconv.Atoi(field[i+1:])
+			message.Reactions = append(message.Reactions, Reaction{Reaction: field[:i], Count: count})
+		}
+	}
+
+	message.Deleted = message.Content == "" && message.File == nil // Un mensaje enviado nunca tiene el contenido vacio
+	return message
+}
+
+/* Funcion
+ * Nombre: splitEscaped
+ * Descripcion: Separa una respuesta del servidor en los separadores que no estan escapados, conservando los escapes
+ * de cada parte para separarla de nuevo o resolverlos con unescape
+ * @text: respuesta o entrada a separar
+ * @sep: separador, ; entre entradas o , entre campos */
+func splitEscaped(text string, sep byte) []string {
+
+	parts := make([]string, 0)
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\': // El siguiente caracter es literal
+			i++
+		case sep:
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+/* Funcion
+ * Nombre: unescape
+ * Descripcion: Resuelve los escapes de un campo de texto de una respuesta: \\, \; y \, */
+func unescape(field string) string {
+
+	if !strings.ContainsRune(field, '\\') {
+		return field
+	}
+
+	var builder strings.Builder
+
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+1 < len(field) {
+			i++
+		}
+		builder.WriteByte(field[i])
+	}
+	return builder.String()
+}
+
+/* Funcion
+ * Nombre: parseDate
+ * Descripcion: Convierte una fecha del protocolo, en la hora local del servidor */
+func parseDate(text string) time.Time {
+
+	date, _ := time.ParseInLocation(dateLayout, text, time.Local)
+	return date
+}
+
+/* Funcion
+ * Nombre: encodeFile
+ * Descripcion: Codifica en base64 un archivo adjunto, vacio si no hay archivo */
+func encodeFile(file []byte) string {
+
+	if len(file) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(file)
+}
@@ -0,0 +1,135 @@
+package main
+
+/* Banco de pruebas de rendimiento del servidor TCP. Conecta miles de clientes repartidos en varios canales,
+ * cada cliente envia sus mensajes y espera a que el servidor los procese, y al final se informa el rendimiento.
+ * El servidor debe iniciarse sin limites de solicitudes, por ejemplo:
+ *   SOCKETCAM_COMMANDRATE=0 SOCKETCAM_MESSAGERATE=0 SOCKETCAM_BYTERATE=0 SOCKETCAM_IPCOMMANDRATE=0 \
+ *   SOCKETCAM_IPMESSAGERATE=0 SOCKETCAM_IPBYTERATE=0 SOCKETCAM_MAXCONNSPERIP=0 */
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Funcion
+ * Nombre: main
+ * Descripcion: Ejecuta el banco de pruebas segun las banderas de la linea de comandos */
+func main() {
+
+	var address string // Direccion del servidor
+	var clients int    // Cantidad de clientes
+	var channels int   // Cantidad de canales
+	var messages int   // Mensajes que envia cada cliente
+
+	flag.StringVar(&address, "e", "localhost:3000", "Server endpoint [ip address]")
+	flag.IntVar(&clients, "c", 2000, "Number of clients")
+	flag.IntVar(&channels, "ch", 100, "Number of channels")
+	flag.IntVar(&messages, "m", 50, "Messages sent by each client")
+	flag.Parse()
+
+	prefix := "bench" + strconv.FormatInt(time.Now().Unix(), 36) // Prefijo para no chocar con canales de ejecuciones anteriores
+
+	// Creamos los canales con un cliente auxiliar, que entra a cada canal para saber cuando fue creado
+	setup, reader := dial(address)
+	for i := 0; i < channels; i++ {
+		fmt.Fprintf(setup, "CREATE %s-%d\n", prefix, i)
+		fmt.Fprintf(setup, "JOIN %s-%d\n", prefix, i)
+	}
+	for joined := 0; joined < channels; {
+		line, err := reader.ReadString('\n')
+		if err != nil || strings.HasPrefix(line, "ERROR ") {
+			log.Fatalln("Failed to create channels: ", err, line)
+		}
+		if strings.HasPrefix(line, "JOIN ") {
+			joined++
+		}
+	}
+	setup.Close()
+
+	// Conectamos los clientes y los unimos a su canal
+	conns := make([]net.Conn, clients)
+	readers := make([]*bufio.Reader, clients)
+
+	for i := range conns {
+		conns[i], readers[i] = dial(address)
+		fmt.Fprintf(conns[i], "JOIN %s-%d\n", prefix, i%channels)
+	}
+
+	var wg sync.WaitGroup
+	latencies := make([]time.Duration, clients)
+	start := time.Now()
+
+	for i := range conns {
+
+		wg.Add(1)
+
+		go func(i int) { // Cada cliente envia sus mensajes y espera la respuesta del canal
+			defer wg.Done()
+
+			channel := prefix + "-" + strconv.Itoa(i%channels)
+			sent := time.Now()
+
+			for m := 0; m < messages; m++ {
+				fmt.Fprintf(conns[i], "MSG %s;;client %d message %d\n", channel, i, m)
+			}
+			fmt.Fprintf(conns[i], "@end LIST_USR %s\n", channel) // Se procesa despues de los mensajes del cliente en el mismo canal
+
+			for { // Descartamos las notificaciones, como los MSG del canal, hasta la confirmacion de la lista de usuarios
+				line, err := readers[i].ReadString('\n')
+				if err != nil {
+					log.Fatalln("Client ", i, ": ", err)
+				}
+				if strings.HasPrefix(line, "ERROR ") || strings.HasPrefix(line, "@end ERROR ") {
+					log.Fatalln("Client ", i, ": ", line)
+				}
+				if line == "DONE end\n" {
+					break
+				}
+			}
+			latencies[i] = time.Since(sent)
+		}(i)
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var total time.Duration
+	var max time.Duration
+
+	for _, latency := range latencies {
+		total += latency
+		if latency > max {
+			max = latency
+		}
+	}
+
+	commands := clients * (messages + 1)
+
+	fmt.Printf("clients: %d, channels: %d, messages per client: %d\n", clients, channels, messages)
+	fmt.Printf("elapsed: %v, throughput: %.0f commands/s\n", elapsed, float64(commands)/elapsed.Seconds())
+	fmt.Printf("client latency: avg %v, max %v\n", total/time.Duration(clients), max)
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+/* Funcion
+ * Nombre: dial
+ * Descripcion: Conecta un cliente al servidor
+ * @address: direccion del servidor */
+func dial(address string) (net.Conn, *bufio.Reader) {
+
+	conn, err := net.Dial("tcp", address)
+
+	if err != nil {
+		log.Fatalln("Failed to connect: ", err)
+	}
+	return conn, bufio.NewReader(conn)
+}
@@ -0,0 +1,96 @@
+package main
+
+/* Bot de ejemplo que se conecta al servidor por TCP con el SDK de bots. Da la bienvenida a quienes entran a sus
+ * canales, responde !help, !ping y !time, y puede enviar un recordatorio periodico, por ejemplo:
+ *   go run ./cmd/bot -e localhost:3000 -n helper -c general,dev -remind 1h -reminder "stand-up in 5 minutes" */
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pipeduque/go-server/bot"
+	"github.com/pipeduque/go-server/client"
+)
+
+/* Funcion
+ * Nombre: main
+ * Descripcion: Ejecuta el bot segun las banderas de la linea de comandos */
+func main() {
+
+	var address string       // Direccion del servidor
+	var nickname string      // Nombre del bot
+	var password string      // Contraseña del nombre del bot
+	var channels string      // Canales separados por comas
+	var welcome string       // Bienvenida, vacia para no darla
+	var remind time.Duration // Intervalo del recordatorio, cero para no enviarlo
+	var reminder string      // Texto del recordatorio
+
+	flag.StringVar(&address, "e", "localhost:3000", "Server endpoint [ip address]")
+	flag.StringVar(&nickname, "n", "bot", "Nickname of the bot")
+	flag.StringVar(&password, "p", "", "Password of the nickname, so no one else can register it")
+	flag.StringVar(&channels, "c", "general", "Channels to join, separated by commas")
+	flag.StringVar(&welcome, "welcome", "welcome to {channel}, {nickname}! Type !help for the commands", "Welcome message, empty to disable")
+	flag.DurationVar(&remind, "remind", 0, "Interval between reminders, 0 to disable")
+	flag.StringVar(&reminder, "reminder", "", "Reminder sent to every channel")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	conn, err := client.Connect(ctx, address, client.Options{Nickname: nickname, Password: password})
+	if err != nil {
+		log.Fatalln("Failed to connect: ", err)
+	}
+	defer conn.Close()
+
+	b := bot.New(strings.Split(channels, ",")...)
+
+	if welcome != "" {
+		b.OnJoin(func(event *bot.Event) {
+			text := strings.NewReplacer("{channel}", event.Channel, "{nickname}", event.Nickname).Replace(welcome)
+			reply(event, text)
+		})
+	}
+
+	b.Command("help", "list the commands", func(event *bot.Event) {
+		reply(event, strings.Join(b.Help(), " | "))
+	})
+
+	b.Command("ping", "check that the bot is alive", func(event *bot.Event) {
+		reply(event, "pong")
+	})
+
+	b.Command("time", "show the time of the bot", func(event *bot.Event) {
+		reply(event, time.Now().Format(time.RFC1123))
+	})
+
+	if remind > 0 && reminder != "" {
+		b.Every(remind, func(event *bot.Event) {
+			for _, channel := range b.Channels {
+				if err := event.Send(channel, reminder); err != nil {
+					log.Println("Failed to send the reminder: ", err)
+				}
+			}
+		})
+	}
+
+	log.Println("Bot", nickname, "running in", channels)
+
+	if err := b.Run(ctx, bot.Remote(conn)); err != nil && err != context.Canceled {
+		log.Fatalln("Bot stopped: ", err)
+	}
+}
+
+/* Funcion
+ * Nombre: reply
+ * Descripcion: Responde en el canal del evento, registrando si no se pudo */
+func reply(event *bot.Event, text string) {
+
+	if err := event.Reply(text); err != nil {
+		log.Println("Failed to reply: ", err)
+	}
+}
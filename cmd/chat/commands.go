@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pipeduque/go-server/client"
+	"github.com/pipeduque/go-server/models"
+)
+
+// Ayuda de los comandos del cliente
+var help = []string{
+	"/nick [nickname] [password?]  register your nickname",
+	"/create [channel]             create a channel and join it",
+	"/join [channel] [password?]   join a channel",
+	"/leave [channel?]             leave the current channel or the given one",
+	"/msg [channel] [text]         send a message to a channel",
+	"/dm [nickname] [text?]        send a direct message or open the conversation",
+	"/file [path] [caption?]       send a file from disk to the current channel or conversation",
+	"/list                         list the channels",
+	"/users                        list the users of the current channel",
+	"/topic [topic?]               show or set the topic of the current channel",
+	"/mode [mode] [password?]      change the modes of the current channel, like +p, -i or +k",
+	"/invite [nickname]            invite a user to the current channel",
+	"/reply [id] [text]            reply to a message of the current channel, in its thread",
+	"/thread [id]                  show a message of the current channel and its replies",
+	"/react [id] [reaction]        react to a message of the current channel with an emoji or a word",
+	"/unreact [id] [reaction]      remove your reaction to a message of the current channel",
+	"/edit [id] [text]             edit a message of the current channel, by its #id",
+	"/delete [id]                  delete a message of the current channel",
+	"/dms                          list your direct conversations",
+	"/close                        close the current conversation",
+	"/quote [COMMAND] [arg;;arg?]  send a raw protocol request",
+	"/quit                         exit",
+	"Text without a command is sent to the current channel or conversation.",
+}
+
+// Errores de los comandos del cliente
+var (
+	errNoChannel = errors.New("this command needs a channel view, use /join")
+	errNoTarget  = errors.New("switch to a channel or a conversation to send messages")
+)
+
+/* Funcion
+ * Nombre: submit
+ * Descripcion: Ejecuta una linea escrita en la entrada, un comando o un mensaje para la vista actual
+ * @text: linea escrita */
+func (c *chat) submit(text string) {
+
+	var err error
+
+	if strings.HasPrefix(text, "/") {
+		name, args := split2(text[1:])
+		err = c.command(strings.ToLower(name), strings.TrimSpace(args))
+	} else {
+		err = c.say(text, nil)
+	}
+
+	if err != nil {
+		c.note(err.Error(), styleError)
+	}
+}
+
+/* Funcion
+ * Nombre: command
+ * Descripcion: Ejecuta un comando del cliente con las solicitudes del cliente del protocolo
+ * @name: nombre del comando sin la barra
+ * @args: argumentos del comando */
+func (c *chat) command(name string, args string) error {
+
+	first, rest := split2(args)
+
+	switch name {
+
+	case "help":
+		for _, text := range help {
+			c.note(text, styleNote)
+		}
+
+	case "nick":
+		if first == "" {
+			return errors.New("usage: /nick [nickname] [password?]")
+		}
+		c.register(first, rest)
+
+	case "create":
+		if first == "" {
+			return errors.New("usage: /create [channel]")
+		}
+		v := c.view()
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			err := cl.Create(ctx, first)
+			return func() {
+				if err != nil {
+					v.add(err.Error(), styleError)
+				}
+				if err == nil || err == client.ErrChannelExists { // Entramos tambien al canal que ya existia
+					c.join(first, "")
+				}
+			}
+		})
+
+	case "join":
+		if first == "" {
+			return errors.New("usage: /join [channel] [password?]")
+		}
+		c.join(first, rest)
+
+	case "leave":
+		if first == "" {
+			if c.view().kind != viewChannel {
+				return errNoChannel
+			}
+			first = c.view().name
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			return cl.Leave(ctx, first)
+		}, func() {
+			c.close(viewChannel, first)
+			c.views[0].add("left "+first, styleNote)
+		})
+
+	case "msg":
+		if first == "" || rest == "" {
+			return errors.New("usage: /msg [channel] [text]")
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error { // Si la vista del canal esta abierta lo muestra su evento
+			return cl.Send(ctx, first, rest, nil)
+		}, nil)
+
+	case "dm":
+		if first == "" {
+			return errors.New("usage: /dm [nickname] [text?]")
+		}
+		_, exists := c.find(viewDirect, first)
+		v := c.open(viewDirect, first)
+		c.show(v)
+		if rest != "" {
+			return c.say(rest, nil)
+		}
+		if !exists {
+			c.loadHistory(v)
+		}
+
+	case "file":
+		if first == "" {
+			return errors.New("usage: /file [path] [caption?]")
+		}
+		data, err := os.ReadFile(first)
+		if err != nil {
+			return err
+		}
+		if rest == "" {
+			rest = filepath.Base(first)
+		}
+		return c.say(rest, data)
+
+	case "list":
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			channels, err := cl.ListChannels(ctx)
+			return func() {
+				if err != nil {
+					c.views[0].add(err.Error(), styleError)
+					return
+				}
+				c.views[0].add("channels:", styleNote)
+				for _, channel := range channels {
+					c.views[0].add(fmt.Sprintf("  %s (%d members) %s", channel.Name, channel.Members, channel.Topic), styleNormal)
+				}
+				c.show(c.views[0])
+			}
+		})
+
+	case "dms":
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			conversations, err := cl.ListDirects(ctx)
+			return func() {
+				if err != nil {
+					c.views[0].add(err.Error(), styleError)
+					return
+				}
+				c.views[0].add("direct conversations:", styleNote)
+				for _, conversation := range conversations {
+					c.views[0].add(fmt.Sprintf("  @%s (%d messages)", conversation.Nickname, conversation.Messages), styleNormal)
+				}
+				c.show(c.views[0])
+			}
+		})
+
+	case "users", "topic", "mode", "invite", "reply", "thread", "react", "unreact", "edit", "delete":
+		if c.view().kind != viewChannel {
+			return errNoChannel
+		}
+		return c.channelCommand(name, c.view().name, args)
+
+	case "close":
+		if c.view().kind != viewDirect {
+			return errors.New("only conversations can be closed, use /leave for channels")
+		}
+		c.close(viewDirect, c.view().name)
+
+	case "quote":
+		return c.quote(args)
+
+	case "quit":
+		c.quit = true
+
+	default:
+		return errors.New("unknown command /" + name + ", try /help")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: channelCommand
+ * Descripcion: Ejecuta los comandos que se aplican al canal de la vista actual
+ * @name: nombre del comando sin la barra
+ * @channel: canal de la vista actual
+ * @args: argumentos del comando */
+func (c *chat) channelCommand(name string, channel string, args string) error {
+
+	first, rest := split2(args)
+	v := c.view()
+
+	switch name {
+
+	case "users":
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			users, err := cl.ListUsers(ctx, channel)
+			return func() {
+				if err != nil {
+					v.add(err.Error(), styleError)
+				} else {
+					v.add("users: "+strings.Join(users, ", "), styleNote)
+				}
+			}
+		})
+
+	case "topic":
+		if args != "" {
+			c.do(func(ctx context.Context, cl *client.Client) error {
+				return cl.SetTopic(ctx, channel, args, "")
+			}, nil)
+			return nil
+		}
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			topic, description, err := cl.Topic(ctx, channel)
+			return func() {
+				if err != nil {
+					v.add(err.Error(), styleError)
+				} else {
+					v.add("topic: "+topic+", "+description, styleNote)
+				}
+			}
+		})
+
+	case "mode":
+		if first == "" {
+			return errors.New("usage: /mode [mode] [password?]")
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			return cl.SetMode(ctx, channel, first, rest)
+		}, nil)
+
+	case "invite":
+		if first == "" {
+			return errors.New("usage: /invite [nickname]")
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			return cl.Invite(ctx, channel, first)
+		}, nil)
+
+	case "reply":
+		id, err := strconv.ParseUint(strings.TrimPrefix(first, "#"), 10, 64)
+		if err != nil || rest == "" {
+			return errors.New("usage: /reply [id] [text]")
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			return cl.Reply(ctx, channel, id, rest, nil)
+		}, nil)
+
+	case "thread":
+		id, err := strconv.ParseUint(strings.TrimPrefix(first, "#"), 10, 64)
+		if err != nil {
+			return errors.New("usage: /thread [id]")
+		}
+		c.async(func(ctx context.Context, cl *client.Client) func() {
+			messages, err := cl.Thread(ctx, channel, id)
+			return func() {
+				if err != nil {
+					v.add(err.Error(), styleError)
+					return
+				}
+				for i, message := range messages {
+					if i == 0 {
+						v.add("thread "+formatMessage(message), styleNote)
+					} else {
+						v.add("  "+formatMessage(message), styleNormal)
+					}
+				}
+			}
+		})
+
+	case "react", "unreact":
+		id, err := strconv.ParseUint(strings.TrimPrefix(first, "#"), 10, 64)
+		if err != nil || rest == "" {
+			return errors.New("usage: /" + name + " [id] [reaction]")
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			if name == "react" {
+				return cl.React(ctx, channel, id, rest)
+			}
+			return cl.Unreact(ctx, channel, id, rest)
+		}, nil)
+
+	case "edit", "delete":
+		id, err := strconv.ParseUint(strings.TrimPrefix(first, "#"), 10, 64)
+		if err != nil || (name == "edit" && rest == "") {
+			return errors.New(map[string]string{"edit": "usage: /edit [id] [text]", "delete": "usage: /delete [id]"}[name])
+		}
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			if name == "edit" {
+				return cl.Edit(ctx, channel, id, rest)
+			}
+			return cl.Delete(ctx, channel, id)
+		}, nil)
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: quote
+ * Descripcion: Envia una solicitud del protocolo escrita a mano y muestra sus respuestas en la vista actual
+ * @request: solicitud COMANDO arg;;arg */
+func (c *chat) quote(request string) error {
+
+	name, args := split2(request)
+	id, ok := commandID(strings.ToUpper(name))
+
+	if !ok {
+		return errors.New("usage: /quote [COMMAND] [arg;;arg?]")
+	}
+
+	var parts []string
+	if args != "" {
+		parts = strings.Split(args, ";;")
+	}
+
+	v := c.view()
+
+	c.async(func(ctx context.Context, cl *client.Client) func() {
+		lines, err := cl.Do(ctx, id, parts...)
+		return func() {
+			for _, line := range lines {
+				v.add(line, styleNote)
+			}
+			if err != nil {
+				v.add(err.Error(), styleError)
+			}
+		}
+	})
+	return nil
+}
+
+/* Funcion
+ * Nombre: say
+ * Descripcion: Envia un mensaje al canal o la conversacion de la vista actual y pide su historial para mostrarlo
+ * @text: contenido del mensaje
+ * @file: archivo adjunto opcional, se envia en base64 */
+func (c *chat) say(text string, file []byte) error {
+
+	v := c.view()
+
+	if v.kind != viewChannel && v.kind != viewDirect {
+		return errNoTarget
+	}
+
+	// Manejamos que la solicitud quepa en el servidor: comando, separadores, salto de linea y archivo en base64
+	size := len("MSG ;;;;\n") + len(v.name) + len(text) + base64.StdEncoding.EncodedLen(len(file))
+	if size > c.maxSize {
+		return errors.New("message too large for the server, the limit is " + sizeText(c.maxSize))
+	}
+
+	name, kind := v.name, v.kind
+
+	if kind == viewChannel { // El mensaje se muestra cuando llega su evento, como los de los demas miembros
+		c.do(func(ctx context.Context, cl *client.Client) error {
+			return cl.Send(ctx, name, text, file)
+		}, nil)
+		return nil
+	}
+
+	c.do(func(ctx context.Context, cl *client.Client) error {
+		return cl.Direct(ctx, name, text, file)
+	}, func() { // El servidor no devuelve los mensajes directos propios, los agregamos a la conversacion
+		c.notify(v, formatMessage(client.Message{Date: time.Now(), Author: c.nickname, Content: text, File: file}), styleNormal)
+		v.seen++
+	})
+	return nil
+}
+
+/* Funcion
+ * Nombre: sizeText
+ * Descripcion: Retorna un tamaño en bytes legible */
+func sizeText(size int) string {
+
+	if size < 1024 {
+		return strconv.Itoa(size) + " bytes"
+	}
+	return strconv.Itoa(size/1024) + " KB"
+}
+
+/* Funcion
+ * Nombre: commandID
+ * Descripcion: Busca el identificador de un comando del protocolo por su nombre */
+func commandID(name string) (models.ID, bool) {
+
+	for id := models.ID(0); id.String() != "UNKNOWN"; id++ {
+		if id.String() == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+/* Funcion
+ * Nombre: split2
+ * Descripcion: Separa el primer argumento de un comando del resto */
+func split2(args string) (string, string) {
+
+	if i := strings.IndexByte(args, ' '); i >= 0 {
+		return args[:i], args[i+1:]
+	}
+	return args, ""
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pipeduque/go-server/client"
+)
+
+// Tiempo maximo de una solicitud de la interfaz
+const requestTimeout = 10 * time.Second
+
+/* Funcion
+ * Nombre: connect
+ * Descripcion: Se conecta al servidor en otra rutina, reintentando con esperas crecientes hasta lograrlo. Las
+ * reconexiones posteriores las maneja el cliente del protocolo */
+func (c *chat) connect() {
+
+	go func() {
+
+		delay := time.Second
+
+		for {
+			cl, err := client.Connect(context.Background(), c.address, client.Options{})
+
+			if err == nil {
+				c.results <- func() { c.connected(cl) }
+				return
+			}
+
+			status := "cannot connect: " + err.Error() + ", retrying in " + delay.String()
+			c.results <- func() { c.status = status }
+
+			time.Sleep(delay)
+			if delay *= 2; delay > 30*time.Second {
+				delay = 30 * time.Second
+			}
+		}
+	}()
+}
+
+/* Funcion
+ * Nombre: connected
+ * Descripcion: Empieza a usar el cliente ya conectado y registra el nombre pedido */
+func (c *chat) connected(cl *client.Client) {
+
+	c.client = cl
+	c.events, c.unsubscribe = cl.Subscribe(256)
+	c.online = true
+	c.status = "connected to " + c.address
+	c.views[0].add("connected to "+c.address, styleNote)
+
+	if c.nickname != "" {
+		c.register(c.nickname, c.password)
+	}
+}
+
+/* Funcion
+ * Nombre: async
+ * Descripcion: Ejecuta una solicitud sin bloquear la interfaz. La funcion que retorna la solicitud se ejecuta
+ * despues en la rutina principal, para aplicar el resultado a la interfaz */
+func (c *chat) async(call func(ctx context.Context, cl *client.Client) func()) {
+
+	if c.client == nil {
+		c.note("cannot send: not connected", styleError)
+		return
+	}
+
+	cl := c.client
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		c.results <- call(ctx, cl)
+	}()
+}
+
+/* Funcion
+ * Nombre: do
+ * Descripcion: Ejecuta una solicitud sin respuesta, informando el error en la vista desde donde se pidio
+ * @then: se ejecuta si la solicitud fue exitosa, puede ser nil */
+func (c *chat) do(call func(ctx context.Context, cl *client.Client) error, then func()) {
+
+	v := c.view()
+
+	c.async(func(ctx context.Context, cl *client.Client) func() {
+
+		err := call(ctx, cl)
+
+		return func() {
+			if err != nil {
+				v.add(err.Error(), styleError)
+			} else if then != nil {
+				then()
+			}
+		}
+	})
+}
+
+/* Funcion
+ * Nombre: register
+ * Descripcion: Registra el nombre del cliente, el cliente del protocolo lo vuelve a registrar al reconectar */
+func (c *chat) register(nickname string, password string) {
+
+	c.do(func(ctx context.Context, cl *client.Client) error {
+		return cl.Register(ctx, nickname, password)
+	}, func() {
+		c.nickname, c.password = nickname, password
+
+		if password == "" {
+			c.views[0].add("registered as "+nickname+" (guest, /nick with a password for direct messages)", styleNote)
+		} else {
+			c.views[0].add("registered as "+nickname, styleNote)
+		}
+	})
+}
+
+/* Funcion
+ * Nombre: join
+ * Descripcion: Entra a un canal y muestra su vista con el historial */
+func (c *chat) join(channel string, password string) {
+
+	c.do(func(ctx context.Context, cl *client.Client) error {
+		return cl.Join(ctx, channel, password)
+	}, func() {
+		v := c.open(viewChannel, channel)
+		c.show(v)
+		c.loadHistory(v)
+	})
+}
+
+/* Funcion
+ * Nombre: loadHistory
+ * Descripcion: Pide el historial de un canal o una conversacion y agrega los mensajes nuevos a la vista */
+func (c *chat) loadHistory(v *view) {
+
+	name, kind := v.name, v.kind
+
+	if kind == viewServer {
+		return
+	}
+
+	c.async(func(ctx context.Context, cl *client.Client) func() {
+
+		var messages []client.Message
+		var err error
+
+		switch kind {
+		case viewChannel:
+			messages, err = cl.ListMessages(ctx, name)
+		case viewDirect:
+			messages, err = cl.ListDirectMessages(ctx, name)
+		}
+
+		return func() {
+			if err == nil { // Los errores del historial no se informan, la vista conserva lo que ya mostraba
+				c.appendHistory(v, messages)
+			}
+		}
+	})
+}
+
+/* Funcion
+ * Nombre: appendHistory
+ * Descripcion: Agrega a la vista los mensajes del historial que aun no mostraba */
+func (c *chat) appendHistory(v *view, messages []client.Message) {
+
+	if v.kind == viewChannel { // Los identificadores crecen, solo agregamos los posteriores al ultimo mostrado
+		for _, message := range messages {
+			c.showMessage(v, message)
+		}
+		return
+	}
+
+	if len(messages) < v.seen { // Se eliminaron mensajes, empezamos de nuevo desde el historial actual
+		v.seen = 0
+	}
+
+	for _, message := range messages[v.seen:] {
+		c.notify(v, formatMessage(message), styleNormal)
+	}
+	v.seen = len(messages)
+}
+
+/* Funcion
+ * Nombre: showMessage
+ * Descripcion: Agrega a la vista de un canal un mensaje que aun no mostraba, del historial o de un evento */
+func (c *chat) showMessage(v *view, message client.Message) {
+
+	if message.ID <= v.last {
+		return
+	}
+	v.last = message.ID
+
+	text := formatMessage(message)
+	if message.Parent != 0 {
+		text = fmt.Sprintf("reply to #%d: %s", message.Parent, text)
+	}
+	c.notify(v, text, styleNormal)
+}
+
+/* Funcion
+ * Nombre: handle
+ * Descripcion: Aplica a la interfaz un evento del servidor */
+func (c *chat) handle(event client.Event) {
+
+	switch event.Type {
+
+	case client.EventConnected: // El cliente del protocolo ya volvio a registrar el nombre y a entrar a los canales
+		c.online = true
+		c.status = "connected to " + c.address
+		c.views[0].add("reconnected to "+c.address, styleNote)
+		for _, v := range c.views { // Solo se agregan los mensajes que llegaron mientras no estabamos conectados
+			c.loadHistory(v)
+		}
+
+	case client.EventDisconnected:
+		c.online = false
+		c.status = "disconnected, reconnecting"
+		c.views[0].add("connection lost: "+event.Text, styleError)
+
+	case client.EventJoin, client.EventLeave:
+		if event.Nickname == c.client.Name() { // Las entradas y salidas propias se muestran al completar la solicitud
+			return
+		}
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			verb := map[string]string{client.EventJoin: " joined", client.EventLeave: " left"}[event.Type]
+			c.views[i].add(event.Nickname+verb, styleNote)
+		}
+
+	case client.EventTopic:
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			c.views[i].topic = event.Text
+			c.views[i].add("topic: "+event.Text, styleNote)
+		}
+
+	case client.EventMode:
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			c.views[i].add("modes: "+event.Text, styleNote)
+		}
+
+	case client.EventMessage: // Incluye las respuestas de los hilos, asi que los eventos reply no se muestran aparte
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			c.showMessage(c.views[i], *event.Message)
+		}
+
+	case client.EventReact, client.EventUnreact:
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			verb := map[string]string{client.EventReact: " reacted ", client.EventUnreact: " removed the reaction "}[event.Type]
+			c.views[i].add(fmt.Sprintf("%s%s%s to #%d", event.Nickname, verb, event.Text, event.Message.ID), styleNote)
+		}
+
+	case client.EventEdit, client.EventDelete:
+		if i, ok := c.find(viewChannel, event.Channel); ok {
+			text := fmt.Sprintf("#%d deleted", event.Message.ID)
+			if event.Type == client.EventEdit {
+				text = fmt.Sprintf("#%d edited: %s", event.Message.ID, event.Message.Content)
+			}
+			c.views[i].add(text, styleNote)
+		}
+
+	case client.EventInvite:
+		c.notify(c.views[0], event.Nickname+" invited you to "+event.Channel+", /join "+event.Channel, styleNotice)
+
+	case client.EventDirect:
+		if i, ok := c.find(viewDirect, event.Nickname); ok {
+			c.notify(c.views[i], formatMessage(*event.Message), styleNormal)
+			c.views[i].seen++ // El mensaje ya es parte del historial de la conversacion
+		} else { // Conversacion nueva, pedimos su historial que incluye este mensaje
+			c.loadHistory(c.open(viewDirect, event.Nickname))
+		}
+
+	case client.EventNotice:
+		c.notify(c.views[0], event.Text, styleNotice)
+		if c.current != 0 {
+			c.note(event.Text, styleNotice)
+		}
+
+	case client.EventError:
+		c.note(event.Text, styleError)
+	}
+}
+
+/* Funcion
+ * Nombre: formatMessage
+ * Descripcion: Arma la linea de un mensaje con su hora, autor y archivo adjunto */
+func formatMessage(message client.Message) string {
+
+	text := message.Author + ": " + message.Content
+
+	if message.Deleted {
+		text = message.Author + ": (deleted)"
+	}
+	if message.ID > 0 { // Los mensajes de los canales muestran su identificador para /edit y /delete
+		text = fmt.Sprintf("#%d ", message.ID) + text
+	}
+	if !message.Date.IsZero() {
+		text = message.Date.Format("15:04") + " " + text
+	}
+	if len(message.File) > 0 {
+		text += fmt.Sprintf(" [file %.1f KB]", float64(len(message.File))/1024)
+	}
+	for _, reaction := range message.Reactions {
+		text += fmt.Sprintf(" [%s %d]", reaction.Reaction, reaction.Count)
+	}
+	if message.Replies > 0 {
+		text += fmt.Sprintf(" (%d replies, /thread %d)", message.Replies, message.ID)
+	}
+	return text
+}
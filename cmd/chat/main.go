@@ -0,0 +1,81 @@
+package main
+
+/* Cliente de chat para la terminal. Muestra la lista de canales y conversaciones, los mensajes de la vista actual
+ * y una linea de entrada donde se escriben los mensajes y los comandos con barra, por ejemplo:
+ *   go run ./cmd/chat -e localhost:3000 -n ana
+ * Si se pierde la conexion se reconecta solo, vuelve a registrar el nombre y a entrar a los canales abiertos */
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+/* Funcion
+ * Nombre: main
+ * Descripcion: Ejecuta el cliente segun las banderas de la linea de comandos */
+func main() {
+
+	var address string  // Direccion del servidor
+	var nickname string // Nombre a registrar al conectarse
+	var password string // Contraseña del nombre
+	var maxSize int     // Tamaño maximo de una solicitud en el servidor
+
+	flag.StringVar(&address, "e", "localhost:3000", "Server endpoint [ip address]")
+	flag.StringVar(&nickname, "n", "", "Nickname to register")
+	flag.StringVar(&password, "p", "", "Password of the nickname, required for direct messages")
+	flag.IntVar(&maxSize, "max", 65536, "Maximum request size of the server in bytes")
+	flag.Parse()
+
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		log.Fatalln("The client needs an interactive terminal: ", err)
+	}
+
+	c := newChat(address, maxSize)
+	c.nickname, c.password = nickname, password // Se registra al conectarse
+	c.views[0].add("type /help for the commands", styleNote)
+	c.connect()
+
+	keys := make(chan []byte)
+
+	go func() { // Leemos las teclas de la terminal
+		buffer := make([]byte, 256)
+		for {
+			n, err := os.Stdin.Read(buffer)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- append([]byte(nil), buffer[:n]...)
+		}
+	}()
+
+	os.Stdout.WriteString("\x1b[?1049h") // Usamos la pantalla alternativa para no borrar la de la terminal
+
+	for !c.quit {
+
+		c.render()
+
+		select {
+
+		case event := <-c.events:
+			c.handle(event)
+
+		case result := <-c.results:
+			result()
+
+		case data, ok := <-keys:
+			if !ok {
+				c.quit = true
+			}
+			c.keys(data)
+		}
+	}
+
+	if c.client != nil {
+		c.unsubscribe()
+		c.client.Close()
+	}
+	os.Stdout.WriteString("\x1b[?1049l")
+	restore()
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// Operaciones para leer y cambiar el modo de la terminal en Linux
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
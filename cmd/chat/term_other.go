@@ -0,0 +1,25 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// Error en los sistemas donde no se puede controlar la terminal
+var errTerminal = errors.New("terminal not supported on this system")
+
+/* Funcion
+ * Nombre: makeRaw
+ * Descripcion: La terminal en modo crudo no esta soportada en este sistema */
+func makeRaw(fd uintptr) (func(), error) {
+
+	return nil, errTerminal
+}
+
+/* Funcion
+ * Nombre: terminalSize
+ * Descripcion: El tamaño de la terminal no esta soportado en este sistema */
+func terminalSize(fd uintptr) (int, int, error) {
+
+	return 0, 0, errTerminal
+}
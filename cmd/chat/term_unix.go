@@ -0,0 +1,63 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+/* Funcion
+ * Nombre: ioctl
+ * Descripcion: Ejecuta una operacion de control sobre el descriptor de la terminal */
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: makeRaw
+ * Descripcion: Pone la terminal en modo crudo, sin eco ni edicion de linea, para leer cada tecla
+ * @fd: descriptor de la terminal
+ * return: @func(): restaura el modo anterior de la terminal
+ *         @error: err si el descriptor no es una terminal */
+func makeRaw(fd uintptr) (func(), error) {
+
+	var old syscall.Termios
+
+	if err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&old)); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctl(fd, ioctlSetTermios, unsafe.Pointer(&old)) }, nil
+}
+
+/* Funcion
+ * Nombre: terminalSize
+ * Descripcion: Retorna las columnas y filas de la terminal */
+func terminalSize(fd uintptr) (int, int, error) {
+
+	var size struct{ rows, cols, x, y uint16 }
+
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, err
+	}
+	return int(size.cols), int(size.rows), nil
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pipeduque/go-server/client"
+)
+
+// Tipos de vista
+const (
+	viewServer  = iota // Consola con los avisos, errores y listados del servidor
+	viewChannel        // Mensajes de un canal
+	viewDirect         // Conversacion directa con un usuario
+)
+
+// Estilos de las lineas con secuencias ANSI
+const (
+	styleNormal = ""
+	styleNote   = "\x1b[2m"  // Entradas y salidas, avisos del cliente
+	styleError  = "\x1b[31m" // Errores del servidor y del cliente
+	styleNotice = "\x1b[33m" // Avisos del servidor
+	styleReset  = "\x1b[0m"
+	styleBar    = "\x1b[7m" // Barras de titulo y estado
+)
+
+// Ancho de la lista de vistas
+const sidebarWidth = 18
+
+// Lineas que se guardan por vista
+const maxLines = 1000
+
+// Estructura para una linea de una vista
+type line struct {
+	text  string
+	style string
+}
+
+// Estructura para una vista de la interfaz: la consola del servidor, un canal o una conversacion directa
+type view struct {
+	kind   int
+	name   string // Nombre del canal o del usuario
+	topic  string
+	lines  []line
+	seen   int    // Mensajes de la conversacion directa ya agregados a las lineas
+	last   uint64 // Identificador del ultimo mensaje del canal agregado a las lineas
+	unread int
+	scroll int // Lineas desplazadas desde el final
+}
+
+/* Funcion
+ * Nombre: title
+ * Descripcion: Retorna el nombre de la vista en la lista */
+func (v *view) title() string {
+
+	switch v.kind {
+	case viewServer:
+		return "server"
+	case viewDirect:
+		return "@" + v.name
+	}
+	return v.name
+}
+
+/* Funcion
+ * Nombre: add
+ * Descripcion: Agrega una linea a la vista, descartando las mas antiguas */
+func (v *view) add(text string, style string) {
+
+	v.lines = append(v.lines, line{text, style})
+
+	if len(v.lines) > maxLines {
+		v.lines = v.lines[len(v.lines)-maxLines:]
+	}
+	if v.scroll > 0 { // Mantenemos fija la posicion de quien esta leyendo mensajes anteriores
+		v.scroll++
+	}
+}
+
+// Estructura con el estado de la interfaz, solo se usa desde la rutina principal
+type chat struct {
+	client      *client.Client      // Nil hasta la primera conexion
+	events      <-chan client.Event // Eventos del servidor, nil hasta la primera conexion
+	unsubscribe func()
+	results     chan func() // Resultados de las solicitudes para aplicar en la rutina principal
+	address     string
+	nickname    string // Nombre registrado en el servidor
+	password    string // Contraseña del nombre, vacia si se registro como invitado
+	online      bool
+	status      string
+	views       []*view
+	current     int
+	input       []rune
+	maxSize     int // Tamaño maximo de una solicitud en el servidor
+	quit        bool
+}
+
+/* Funcion
+ * Nombre: newChat
+ * Descripcion: Crea la interfaz con la vista de la consola del servidor */
+func newChat(address string, maxSize int) *chat {
+
+	return &chat{
+		results: make(chan func(), 64),
+		address: address,
+		status:  "connecting",
+		views:   []*view{{kind: viewServer}},
+		maxSize: maxSize,
+	}
+}
+
+/* Funcion
+ * Nombre: find
+ * Descripcion: Busca una vista, los nombres de los canales no distinguen mayusculas */
+func (c *chat) find(kind int, name string) (int, bool) {
+
+	for i, v := range c.views {
+		if v.kind == kind && (v.name == name || kind == viewChannel && strings.EqualFold(v.name, name)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+/* Funcion
+ * Nombre: open
+ * Descripcion: Retorna una vista, creandola si no existe */
+func (c *chat) open(kind int, name string) *view {
+
+	if i, ok := c.find(kind, name); ok {
+		return c.views[i]
+	}
+
+	v := &view{kind: kind, name: name}
+	c.views = append(c.views, v)
+	return v
+}
+
+/* Funcion
+ * Nombre: close
+ * Descripcion: Elimina una vista, la consola del servidor no se puede eliminar */
+func (c *chat) close(kind int, name string) {
+
+	if i, ok := c.find(kind, name); ok && i > 0 {
+		c.views = append(c.views[:i], c.views[i+1:]...)
+		if c.current >= i {
+			c.current--
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: show
+ * Descripcion: Cambia la vista actual */
+func (c *chat) show(v *view) {
+
+	for i := range c.views {
+		if c.views[i] == v {
+			c.current = i
+			v.unread = 0
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: view
+ * Descripcion: Retorna la vista actual */
+func (c *chat) view() *view {
+
+	return c.views[c.current]
+}
+
+/* Funcion
+ * Nombre: notify
+ * Descripcion: Agrega una linea a una vista y la cuenta como no leida si no es la actual */
+func (c *chat) notify(v *view, text string, style string) {
+
+	v.add(text, style)
+
+	if v != c.view() {
+		v.unread++
+	}
+}
+
+/* Funcion
+ * Nombre: note
+ * Descripcion: Agrega una linea a la vista actual */
+func (c *chat) note(text string, style string) {
+
+	c.view().add(text, style)
+}
+
+/* Funcion
+ * Nombre: keys
+ * Descripcion: Procesa las teclas leidas de la terminal en modo crudo
+ * @data: bytes leidos */
+func (c *chat) keys(data []byte) {
+
+	for i := 0; i < len(data); i++ {
+
+		switch b := data[i]; b {
+
+		case 3: // Ctrl-C
+			c.quit = true
+
+		case 4: // Ctrl-D con la linea vacia
+			if len(c.input) == 0 {
+				c.quit = true
+			}
+
+		case '\r', '\n':
+			text := strings.TrimSpace(string(c.input))
+			c.input = c.input[:0]
+			if text != "" {
+				c.submit(text)
+			}
+
+		case 127, 8: // Borrar
+			if len(c.input) > 0 {
+				c.input = c.input[:len(c.input)-1]
+			}
+
+		case 21: // Ctrl-U borra la linea
+			c.input = c.input[:0]
+
+		case 23: // Ctrl-W borra la ultima palabra
+			text := strings.TrimRight(string(c.input), " ")
+			c.input = []rune(text[:strings.LastIndex(text, " ")+1])
+
+		case '\t':
+			c.show(c.views[(c.current+1)%len(c.views)])
+
+		case 27: // Secuencias de escape de las teclas especiales
+			if i+2 < len(data) && data[i+1] == '[' {
+				i += 2
+				switch data[i] {
+				case 'Z': // Shift-Tab
+					c.show(c.views[(c.current+len(c.views)-1)%len(c.views)])
+				case 'A': // Flecha arriba
+					c.scrollBy(1)
+				case 'B': // Flecha abajo
+					c.scrollBy(-1)
+				case '5', '6': // Re Pag y Av Pag, terminan en ~
+					_, rows := c.size()
+					if data[i] == '5' {
+						c.scrollBy(rows / 2)
+					} else {
+						c.scrollBy(-rows / 2)
+					}
+					if i+1 < len(data) && data[i+1] == '~' {
+						i++
+					}
+				}
+			}
+
+		default:
+			if b >= 32 { // Caracter imprimible, puede ocupar varios bytes
+				r, size := utf8.DecodeRune(data[i:])
+				c.input = append(c.input, r)
+				i += size - 1
+			}
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: scrollBy
+ * Descripcion: Desplaza la vista actual hacia los mensajes anteriores, o hacia los nuevos si es negativo */
+func (c *chat) scrollBy(lines int) {
+
+	v := c.view()
+
+	if v.scroll += lines; v.scroll < 0 {
+		v.scroll = 0
+	}
+	if v.scroll > len(v.lines) {
+		v.scroll = len(v.lines)
+	}
+}
+
+/* Funcion
+ * Nombre: size
+ * Descripcion: Retorna el ancho de la terminal y las filas del panel de mensajes */
+func (c *chat) size() (int, int) {
+
+	width, height, err := terminalSize(os.Stdout.Fd())
+
+	if err != nil || width < sidebarWidth+20 || height < 5 {
+		width, height = 80, 24
+	}
+	return width, height - 3 // Titulo, estado y entrada
+}
+
+/* Funcion
+ * Nombre: render
+ * Descripcion: Dibuja la interfaz completa: titulo, lista de vistas, panel de mensajes, estado y entrada */
+func (c *chat) render() {
+
+	width, rows := c.size()
+	pane := width - sidebarWidth - 1
+	v := c.view()
+
+	var b strings.Builder
+
+	b.WriteString("\x1b[?25l") // Ocultamos el cursor mientras dibujamos
+
+	title := " " + v.title()
+	if v.topic != "" {
+		title += " - " + v.topic
+	}
+	fmt.Fprintf(&b, "\x1b[1;1H%s%s%s", styleBar, fit(title, width), styleReset)
+
+	wrapped := wrap(v.lines, pane, rows+v.scroll)
+	if len(wrapped) > rows+v.scroll {
+		wrapped = wrapped[len(wrapped)-rows-v.scroll:]
+	}
+	wrapped = wrapped[:len(wrapped)-min(v.scroll, len(wrapped))]
+
+	for row := 0; row < rows; row++ {
+
+		fmt.Fprintf(&b, "\x1b[%d;1H", row+2)
+
+		side := ""
+		if row < len(c.views) {
+			item := c.views[row]
+			side = " " + item.title()
+			if item.unread > 0 {
+				side += fmt.Sprintf(" (%d)", item.unread)
+			}
+			if row == c.current {
+				side = ">" + side[1:]
+			}
+		}
+		b.WriteString(fit(side, sidebarWidth) + "|")
+
+		// Alineamos los mensajes con la parte inferior del panel
+		if index := row - (rows - len(wrapped)); index >= 0 {
+			b.WriteString(wrapped[index].style + wrapped[index].text + styleReset)
+		}
+		b.WriteString("\x1b[K")
+	}
+
+	status := " " + c.status
+	if c.nickname != "" {
+		status = " " + c.nickname + " -" + status
+	}
+	if v.scroll > 0 {
+		status += fmt.Sprintf(" - scrolled %d lines", v.scroll)
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H%s%s%s", rows+2, styleBar, fit(status+"  [Tab] views  [PgUp/PgDn] scroll  /help", width), styleReset)
+
+	// La entrada muestra el final del texto si no cabe en la linea
+	input := c.input
+	if len(input) > width-3 {
+		input = input[len(input)-(width-3):]
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H> %s\x1b[K\x1b[?25h", rows+3, string(input))
+
+	os.Stdout.WriteString(b.String())
+}
+
+/* Funcion
+ * Nombre: wrap
+ * Descripcion: Parte las lineas al ancho del panel, armando solo las ultimas filas que se necesitan
+ * @lines: lineas de la vista
+ * @width: ancho del panel
+ * @rows: filas necesarias */
+func wrap(lines []line, width int, rows int) []line {
+
+	wrapped := make([]line, 0, rows)
+
+	for i := len(lines) - 1; i >= 0 && len(wrapped) < rows; i-- {
+
+		text := []rune(lines[i].text)
+		parts := make([]line, 0, 1)
+
+		for len(text) > width {
+			parts = append(parts, line{string(text[:width]), lines[i].style})
+			text = text[width:]
+		}
+		parts = append(parts, line{string(text), lines[i].style})
+
+		wrapped = append(parts, wrapped...)
+	}
+	return wrapped
+}
+
+/* Funcion
+ * Nombre: fit
+ * Descripcion: Recorta o completa con espacios un texto al ancho dado */
+func fit(text string, width int) string {
+
+	runes := []rune(text)
+
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+/* Funcion
+ * Nombre: min
+ * Descripcion: Retorna el menor de dos enteros */
+func min(a int, b int) int {
+
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package main
+
+/* Receptor local de webhooks para probar las suscripciones. Muestra cada entrega con su firma verificada y puede
+ * responder errores para probar los reintentos y el registro de entregas fallidas, por ejemplo:
+ *   go run ./cmd/hookecho -l :9090 -secret clave -fail 2
+ *   curl -X POST localhost:8080/api/admin/webhooks -H 'Authorization: Bearer [token]' \
+ *     -d '{"channel":"general","url":"http://localhost:9090","secret":"clave"}' */
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/pipeduque/go-server/webhook"
+)
+
+/* Funcion
+ * Nombre: main
+ * Descripcion: Ejecuta el receptor segun las banderas de la linea de comandos */
+func main() {
+
+	var address string // Direccion de escucha
+	var secret string  // Clave para verificar las firmas, vacia para no verificarlas
+	var fail int       // Entregas que se responden con 500 antes de aceptar
+	var status int     // Codigo con el que se responden las entregas aceptadas
+
+	flag.StringVar(&address, "l", ":9090", "Listen address")
+	flag.StringVar(&secret, "secret", "", "Secret of the webhook, to verify the signatures")
+	flag.IntVar(&fail, "fail", 0, "Number of deliveries answered with 500 before accepting")
+	flag.IntVar(&status, "status", http.StatusNoContent, "Status code for the accepted deliveries")
+	flag.Parse()
+
+	var mu sync.Mutex
+
+	http.HandleFunc("/", func(writer http.ResponseWriter, reader *http.Request) {
+
+		body, err := io.ReadAll(reader.Body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		signature := "not verified"
+		if secret != "" {
+			signature = "valid signature"
+			if err := webhook.Verify(secret, reader.Header.Get(webhook.TimestampHeader), body, reader.Header.Get(webhook.SignatureHeader)); err != nil {
+				signature = "REJECTED: " + err.Error()
+			}
+		}
+
+		mu.Lock()
+		failing := fail > 0
+		if failing {
+			fail--
+		}
+		mu.Unlock()
+
+		code := status
+		if failing {
+			code = http.StatusInternalServerError
+		}
+
+		log.Printf("%s %s %s -> %d\n%s\n", reader.Header.Get(webhook.EventHeader), reader.Header.Get(webhook.DeliveryHeader), signature, code, body)
+		writer.WriteHeader(code)
+	})
+
+	log.Println("Listening for webhooks on", address)
+	log.Fatalln(http.ListenAndServe(address, nil))
+}
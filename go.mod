@@ -7,4 +7,5 @@ require (
 	github.com/gorilla/websocket v1.5.0
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/urfave/negroni v1.0.0
+	golang.org/x/text v0.3.7
 )
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pipeduque/go-server/models"
+	"github.com/pipeduque/go-server/webhook"
+)
+
+// Estructura para un comando de administracion, llega como JSON por el websocket o se arma desde una ruta REST
+type adminCommand struct {
+	Action   string   `json:"action"`             // clients, kick, ban, bans, delete_channel, delete_message, broadcast, webhooks, add_webhook, delete_webhook, dead_letters, integrations, add_integration o delete_integration
+	Target   string   `json:"target,omitempty"`   // Nombre o direccion del cliente (kick), IP o nombre a banear (ban)
+	Kind     string   `json:"kind,omitempty"`     // Tipo de baneo: ip o user
+	Duration string   `json:"duration,omitempty"` // Duracion del baneo, por ejemplo 10m
+	Channel  string   `json:"channel,omitempty"`  // Canal a eliminar, del mensaje a eliminar, del webhook (* para todos) o de la integracion
+	ID       uint64   `json:"id,omitempty"`       // Identificador del mensaje, del webhook o de la integracion a eliminar
+	Name     string   `json:"name,omitempty"`     // Autor de los mensajes de la integracion
+	Reason   string   `json:"reason,omitempty"`   // Razon que se informa al cliente expulsado o baneado
+	Text     string   `json:"text,omitempty"`     // Texto del aviso (broadcast)
+	URL      string   `json:"url,omitempty"`      // Direccion que recibe las entregas del webhook
+	Secret   string   `json:"secret,omitempty"`   // Clave de la firma del webhook, se genera si no se indica
+	Events   []string `json:"events,omitempty"`   // Eventos del webhook: message, join, leave, channel_created
+}
+
+// Estructura de la respuesta a un comando de administracion por el websocket
+type adminResponse struct {
+	Action string      `json:"action"`
+	OK     bool        `json:"ok"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+/* Funcion
+ * Nombre: runAdmin
+ * Descripcion: Ejecuta un comando de administracion sobre el servidor
+ * @cmd: comando a ejecutar
+ * return: @interface{}: resultado del comando
+ *         @error: err si el comando fallo, envuelve los errores de models para su codigo de estado */
+func runAdmin(cmd adminCommand) (interface{}, error) {
+
+	switch cmd.Action {
+
+	case "clients": // Lista los clientes conectados
+		return server.Clients(), nil
+
+	case "kick": // Desconecta a un cliente
+		return nil, server.Kick(cmd.Target, cmd.Reason)
+
+	case "ban": // Banea una IP o un nombre
+		var duration time.Duration
+
+		if cmd.Duration != "" { // Sin duracion se usa la configurada en el servidor
+			var err error
+			if duration, err = time.ParseDuration(cmd.Duration); err != nil {
+				return nil, fmt.Errorf("%w: invalid duration %q", models.ErrInvalidArgument, cmd.Duration)
+			}
+		}
+		return server.Ban(cmd.Kind, cmd.Target, duration, cmd.Reason)
+
+	case "bans": // Lista los baneos vigentes
+		return server.Bans(), nil
+
+	case "delete_channel": // Elimina un canal
+		return nil, server.DeleteChannel(cmd.Channel)
+
+	case "delete_message": // Elimina un mensaje de un canal
+		return nil, server.DeleteMessage(cmd.Channel, cmd.ID)
+
+	case "broadcast": // Envia un aviso a todos los clientes
+		count, err := server.Broadcast(cmd.Text)
+		return map[string]int{"clients": count}, err
+
+	case "webhooks": // Lista las suscripciones de webhooks
+		return hooks.List(), nil
+
+	case "add_webhook": // Suscribe una direccion a los eventos de un canal
+		return hooks.Add(webhook.Subscription{Channel: cmd.Channel, URL: cmd.URL, Secret: cmd.Secret, Events: cmd.Events})
+
+	case "delete_webhook": // Elimina una suscripcion
+		return nil, hooks.Remove(cmd.ID)
+
+	case "dead_letters": // Lista las entregas de webhooks que fallaron
+		return hooks.DeadLetters(), nil
+
+	case "integrations": // Lista las integraciones que publican en los canales
+		return server.Integrations(), nil
+
+	case "add_integration": // Crea el token de una integracion para un canal
+		return server.CreateIntegration(cmd.Channel, cmd.Name)
+
+	case "delete_integration": // Elimina una integracion
+		return nil, server.DeleteIntegration(cmd.ID)
+	}
+
+	return nil, fmt.Errorf("%w: unknown action %q", models.ErrInvalidArgument, cmd.Action)
+}
+
+/* Funcion
+ * Nombre: adminMessage
+ * Descripcion: Ejecuta un comando de administracion recibido por el websocket y retorna la respuesta en JSON
+ * @request: comando en JSON */
+func adminMessage(request []byte) []byte {
+
+	var cmd adminCommand
+	var response adminResponse
+
+	if err := json.Unmarshal(request, &cmd); err != nil {
+		response = adminResponse{Action: cmd.Action, Error: "invalid command: " + err.Error()}
+	} else if result, err := runAdmin(cmd); err != nil {
+		response = adminResponse{Action: cmd.Action, Error: err.Error()}
+	} else {
+		response = adminResponse{Action: cmd.Action, OK: true, Result: result}
+	}
+
+	encoded, _ := json.Marshal(response)
+	return encoded
+}
+
+/* Funcion
+ * Nombre: adminRoutes
+ * Descripcion: Agrega al enrutador las rutas REST de administracion */
+func adminRoutes(router *mux.Router) {
+
+	admin := router.PathPrefix("/api").Subrouter()
+	admin.Use(requireAdmin) // Todas las rutas de administracion requieren el token
+
+	admin.Methods("POST").Path("/admin/kick").Name("Kick").HandlerFunc(adminBody("kick"))
+	admin.Methods("POST").Path("/admin/bans").Name("Ban").HandlerFunc(adminBody("ban"))
+	admin.Methods("GET").Path("/admin/bans").Name("Bans").HandlerFunc(adminBody("bans"))
+	admin.Methods("POST").Path("/admin/broadcast").Name("Broadcast").HandlerFunc(adminBody("broadcast"))
+	admin.Methods("DELETE").Path("/channels/{channel}").Name("Delete Channel").HandlerFunc(deleteChannel)
+	admin.Methods("DELETE").Path("/channels/{channel}/messages/{id}").Name("Delete Message").HandlerFunc(deleteMessage)
+	admin.Methods("GET").Path("/admin/webhooks").Name("Webhooks").HandlerFunc(adminBody("webhooks"))
+	admin.Methods("POST").Path("/admin/webhooks").Name("Add Webhook").HandlerFunc(adminBody("add_webhook"))
+	admin.Methods("GET").Path("/admin/webhooks/dead-letters").Name("Dead Letters").HandlerFunc(adminBody("dead_letters"))
+	admin.Methods("DELETE").Path("/admin/webhooks/{id}").Name("Delete Webhook").HandlerFunc(adminID("delete_webhook", "webhook"))
+	admin.Methods("GET").Path("/admin/integrations").Name("Integrations").HandlerFunc(adminBody("integrations"))
+	admin.Methods("POST").Path("/admin/integrations").Name("Add Integration").HandlerFunc(adminBody("add_integration"))
+	admin.Methods("DELETE").Path("/admin/integrations/{id}").Name("Delete Integration").HandlerFunc(adminID("delete_integration", "integration"))
+}
+
+/* Funcion
+ * Nombre: adminBody
+ * Descripcion: Retorna un manejador que ejecuta una accion de administracion con los campos del cuerpo JSON
+ * @action: accion a ejecutar */
+func adminBody(action string) http.HandlerFunc {
+
+	return func(writer http.ResponseWriter, reader *http.Request) {
+
+		var cmd adminCommand
+
+		if reader.Method != "GET" { // Las consultas no tienen cuerpo
+			decoder := json.NewDecoder(http.MaxBytesReader(writer, reader.Body, maxBodySize))
+			decoder.DisallowUnknownFields()
+
+			if err := decoder.Decode(&cmd); err != nil {
+				writeJSON(writer, http.StatusBadRequest, apiError{"invalid body: " + err.Error()})
+				return
+			}
+		}
+
+		cmd.Action = action
+		writeAdmin(writer, cmd)
+	}
+}
+
+/* Funcion
+ * Nombre: deleteChannel
+ * Descripcion: Elimina el canal de la ruta */
+func deleteChannel(writer http.ResponseWriter, reader *http.Request) {
+
+	writeAdmin(writer, adminCommand{Action: "delete_channel", Channel: mux.Vars(reader)["channel"]})
+}
+
+/* Funcion
+ * Nombre: deleteMessage
+ * Descripcion: Elimina el mensaje de la ruta */
+func deleteMessage(writer http.ResponseWriter, reader *http.Request) {
+
+	id, err := strconv.ParseUint(mux.Vars(reader)["id"], 10, 64)
+
+	if err != nil {
+		writeJSON(writer, http.StatusBadRequest, apiError{"invalid message id " + strconv.Quote(mux.Vars(reader)["id"])})
+		return
+	}
+	writeAdmin(writer, adminCommand{Action: "delete_message", Channel: mux.Vars(reader)["channel"], ID: id})
+}
+
+/* Funcion
+ * Nombre: adminID
+ * Descripcion: Retorna un manejador que ejecuta una accion de administracion con el identificador de la ruta
+ * @action: accion a ejecutar
+ * @kind: nombre de lo que identifica, para el error */
+func adminID(action string, kind string) http.HandlerFunc {
+
+	return func(writer http.ResponseWriter, reader *http.Request) {
+
+		id, err := strconv.ParseUint(mux.Vars(reader)["id"], 10, 64)
+
+		if err != nil {
+			writeJSON(writer, http.StatusBadRequest, apiError{"invalid " + kind + " id " + strconv.Quote(mux.Vars(reader)["id"])})
+			return
+		}
+		writeAdmin(writer, adminCommand{Action: action, ID: id})
+	}
+}
+
+/* Funcion
+ * Nombre: writeAdmin
+ * Descripcion: Ejecuta un comando de administracion y responde su resultado, 204 si no tiene resultado */
+func writeAdmin(writer http.ResponseWriter, cmd adminCommand) {
+
+	result, err := runAdmin(cmd)
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+
+	if result == nil {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
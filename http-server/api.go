@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pipeduque/go-server/models"
+	"github.com/pipeduque/go-server/webhook"
+)
+
+// Tamaño maximo del cuerpo de una solicitud a la API
+const maxBodySize = 1 << 20
+
+// Estructura del cuerpo para publicar un mensaje en un canal, el autor se publica con el prefijo de administrador
+type postMessageRequest struct {
+	Author  string `json:"author"`
+	Content string `json:"content"`
+	File    string `json:"file"`
+}
+
+// Cuerpo de un mensaje de una integracion, los demas campos se ignoran
+type integrationRequest struct {
+	Content string `json:"content"`
+	Text    string `json:"text"` // Alternativa a content
+	File    string `json:"file"`
+}
+
+/* Funcion
+ * Nombre: apiRoutes
+ * Descripcion: Agrega al enrutador las rutas de la API REST, con el mismo estado del servidor que usan los clientes TCP.
+ * Las consultas de los canales son publicas pero solo muestran los canales con modos a los administradores */
+func apiRoutes(router *mux.Router) {
+
+	api := router.PathPrefix("/api").Subrouter()
+
+	api.Methods("GET").Path("/status").Name("Status").HandlerFunc(getStatus)
+	api.Methods("GET").Path("/clients").Name("Clients").Handler(requireAdmin(http.HandlerFunc(getClients)))
+	api.Methods("GET").Path("/channels").Name("Channels").HandlerFunc(getChannels)
+	api.Methods("GET").Path("/channels/{channel}").Name("Channel").HandlerFunc(getChannel)
+	api.Methods("GET").Path("/channels/{channel}/messages").Name("Channel Messages").HandlerFunc(getMessages)
+	api.Methods("POST").Path("/channels/{channel}/messages").Name("Post Message").Handler(requireAdmin(http.HandlerFunc(postMessage)))
+	api.Methods("GET").Path("/channels/{channel}/members").Name("Channel Members").HandlerFunc(getMembers)
+
+	// Webhooks de entrada, el token identifica la integracion y su canal
+	router.Methods("POST").Path("/hooks/{token}").Name("Incoming Webhook").HandlerFunc(postIntegration)
+}
+
+/* Funcion
+ * Nombre: getStatus
+ * Descripcion: Responde el estado del servidor */
+func getStatus(writer http.ResponseWriter, reader *http.Request) {
+
+	writeJSON(writer, http.StatusOK, server.Status())
+}
+
+/* Funcion
+ * Nombre: getClients
+ * Descripcion: Responde los clientes conectados al servidor TCP */
+func getClients(writer http.ResponseWriter, reader *http.Request) {
+
+	writeJSON(writer, http.StatusOK, server.Clients())
+}
+
+/* Funcion
+ * Nombre: getChannels
+ * Descripcion: Responde los canales del servidor */
+func getChannels(writer http.ResponseWriter, reader *http.Request) {
+
+	writeJSON(writer, http.StatusOK, server.Channels(isAdmin(reader)))
+}
+
+/* Funcion
+ * Nombre: getChannel
+ * Descripcion: Responde los datos de un canal */
+func getChannel(writer http.ResponseWriter, reader *http.Request) {
+
+	channel, err := server.Channel(mux.Vars(reader)["channel"], isAdmin(reader))
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, channel)
+}
+
+/* Funcion
+ * Nombre: getMessages
+ * Descripcion: Responde una pagina del historial de un canal. Se pagina hacia atras con ?before=[id]&limit=[n],
+ * usando como before el valor next de la pagina anterior */
+func getMessages(writer http.ResponseWriter, reader *http.Request) {
+
+	query := reader.URL.Query()
+
+	var before uint64
+	var limit int
+	var err error
+
+	if value := query.Get("before"); value != "" { // Manejamos que el cursor sea un identificador valido
+		if before, err = strconv.ParseUint(value, 10, 64); err != nil {
+			writeJSON(writer, http.StatusBadRequest, apiError{"invalid before " + strconv.Quote(value)})
+			return
+		}
+	}
+
+	if value := query.Get("limit"); value != "" { // Manejamos que el limite sea un numero positivo
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			writeJSON(writer, http.StatusBadRequest, apiError{"invalid limit " + strconv.Quote(value)})
+			return
+		}
+	}
+
+	page, err := server.Messages(mux.Vars(reader)["channel"], before, limit, isAdmin(reader))
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, page)
+}
+
+/* Funcion
+ * Nombre: postMessage
+ * Descripcion: Publica un mensaje de un administrador en un canal con el cuerpo {"author", "content", "file"}.
+ * El autor se publica como admin-[author], un nombre que ningun cliente puede registrar */
+func postMessage(writer http.ResponseWriter, reader *http.Request) {
+
+	var body postMessageRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, reader.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil { // Manejamos que el cuerpo sea un JSON valido
+		writeJSON(writer, http.StatusBadRequest, apiError{"invalid body: " + err.Error()})
+		return
+	}
+
+	message, err := server.PostMessage(mux.Vars(reader)["channel"], models.AdminPrefix+body.Author, []byte(body.Content), []byte(body.File))
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusCreated, message)
+}
+
+/* Funcion
+ * Nombre: postIntegration
+ * Descripcion: Publica en su canal el mensaje de una integracion, con el cuerpo {"content", "file"}. Tambien acepta
+ * {"text"}, el formato que envian muchas herramientas de CI y monitoreo */
+func postIntegration(writer http.ResponseWriter, reader *http.Request) {
+
+	var body integrationRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(writer, reader.Body, maxBodySize)).Decode(&body); err != nil {
+		writeJSON(writer, http.StatusBadRequest, apiError{"invalid body: " + err.Error()})
+		return
+	}
+
+	if body.Content == "" {
+		body.Content = body.Text
+	}
+
+	message, err := server.PostIntegration(mux.Vars(reader)["token"], []byte(body.Content), []byte(body.File))
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusCreated, message)
+}
+
+/* Funcion
+ * Nombre: getMembers
+ * Descripcion: Responde los miembros de un canal */
+func getMembers(writer http.ResponseWriter, reader *http.Request) {
+
+	members, err := server.Members(mux.Vars(reader)["channel"], isAdmin(reader))
+
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, members)
+}
+
+// Estructura de la respuesta de error de la API
+type apiError struct {
+	Error string `json:"error"`
+}
+
+/* Funcion
+ * Nombre: writeError
+ * Descripcion: Responde un error del servidor con su codigo de estado */
+func writeError(writer http.ResponseWriter, err error) {
+
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, models.ErrChannelNotFound), errors.Is(err, models.ErrClientNotFound), errors.Is(err, models.ErrMessageNotFound), errors.Is(err, webhook.ErrWebhookNotFound),
+		errors.Is(err, models.ErrIntegrationNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrInvalidToken):
+		status = http.StatusUnauthorized
+	case errors.Is(err, models.ErrInvalidMessage), errors.Is(err, models.ErrInvalidArgument):
+		status = http.StatusBadRequest
+	}
+	writeJSON(writer, status, apiError{err.Error()})
+}
+
+/* Funcion
+ * Nombre: writeJSON
+ * Descripcion: Responde un valor en JSON con el codigo de estado dado */
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		logger.Warn("api write failed", "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/* Funcion
+ * Nombre: loadAdminToken
+ * Descripcion: Retorna el token de administracion configurado. Sin token configurado genera uno aleatorio y lo
+ * registra, asi la administracion nunca queda abierta
+ * return: @string: token de administracion
+ *         @error: err si no se pudo generar el token */
+func loadAdminToken() (string, error) {
+
+	if config.AdminToken != "" {
+		return config.AdminToken, nil
+	}
+
+	token := make([]byte, 24)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	generated := hex.EncodeToString(token)
+	logger.Warn("SOCKETCAM_ADMINTOKEN not set, generated an admin token for this run", "token", generated)
+	return generated, nil
+}
+
+/* Funcion
+ * Nombre: requireAdmin
+ * Descripcion: Middleware que solo deja pasar las solicitudes con el token de administracion, en la cabecera
+ * Authorization: Bearer [token] o en el parametro token de la URL, que usan el websocket y EventSource del navegador
+ * @next: manejador protegido */
+func requireAdmin(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, reader *http.Request) {
+
+		if !isAdmin(reader) {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(writer, http.StatusUnauthorized, apiError{"missing or invalid admin token"})
+			return
+		}
+		next.ServeHTTP(writer, reader)
+	})
+}
+
+/* Funcion
+ * Nombre: isAdmin
+ * Descripcion: Indica si la solicitud trae el token de administracion, comparado en tiempo constante */
+func isAdmin(reader *http.Request) bool {
+
+	token := reader.URL.Query().Get("token")
+
+	if header := reader.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+/* Funcion
+ * Nombre: checkOrigin
+ * Descripcion: Acepta el websocket solo desde paginas del propio panel, asi otro sitio no puede abrirlo con la
+ * sesion del navegador. Los clientes que no son navegadores no envian Origin y se autentican con el token
+ * return: @bool: true si el origen es el host configurado, o el de la solicitud si no se configuro */
+func checkOrigin(reader *http.Request) bool {
+
+	origin := reader.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	target, err := url.Parse(origin)
+
+	if err != nil {
+		return false
+	}
+
+	host := config.Host
+
+	if host == "" {
+		host = reader.Host
+	}
+	return strings.EqualFold(target.Host, host)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pipeduque/go-server/models"
+)
+
+// Intervalo entre los eventos de estadisticas del panel
+const statsInterval = time.Second
+
+// Eventos que se guardan para cada panel mientras no los lee
+const eventBuffer = 1024
+
+// Estructura con el estado completo del servidor para iniciar el panel
+type snapshot struct {
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Status   models.Status        `json:"status"`
+	Clients  []models.ClientInfo  `json:"clients"`
+	Channels []models.ChannelInfo `json:"channels"`
+}
+
+/* Funcion
+ * Nombre: eventRoutes
+ * Descripcion: Agrega al enrutador las rutas del estado y los eventos del servidor para el panel, que muestran todos
+ * los clientes y canales y por eso requieren el token de administracion */
+func eventRoutes(router *mux.Router) {
+
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(requireAdmin)
+
+	api.Methods("GET").Path("/snapshot").Name("Snapshot").HandlerFunc(getSnapshot)
+	api.Methods("GET").Path("/events").Name("Events").HandlerFunc(getEvents)
+}
+
+/* Funcion
+ * Nombre: takeSnapshot
+ * Descripcion: Retorna el estado completo del servidor */
+func takeSnapshot() snapshot {
+
+	return snapshot{
+		Type:     "snapshot",
+		Time:     time.Now(),
+		Status:   server.Status(),
+		Clients:  server.Clients(),
+		Channels: server.Channels(true),
+	}
+}
+
+/* Funcion
+ * Nombre: getSnapshot
+ * Descripcion: Responde el estado completo del servidor */
+func getSnapshot(writer http.ResponseWriter, reader *http.Request) {
+
+	writeJSON(writer, http.StatusOK, takeSnapshot())
+}
+
+/* Funcion
+ * Nombre: getEvents
+ * Descripcion: Envia los eventos del servidor como Server-Sent Events, un JSON por evento. El primero es el estado
+ * completo del servidor y cada statsInterval se envia un evento stats con el estado y los contadores acumulados */
+func getEvents(writer http.ResponseWriter, reader *http.Request) {
+
+	flusher, ok := writer.(http.Flusher)
+
+	if !ok { // Manejamos que la respuesta permita enviar los eventos a medida que ocurren
+		writeJSON(writer, http.StatusInternalServerError, apiError{"streaming not supported"})
+		return
+	}
+
+	events, cancel := server.Subscribe(eventBuffer) // Nos suscribimos antes del estado para no perder eventos
+	defer cancel()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	send := func(value interface{}) bool {
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			logger.Warn("event encoding failed", "error", err)
+			return true
+		}
+
+		if _, err := writer.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send(takeSnapshot()) {
+		return
+	}
+
+	for {
+		select {
+
+		case <-reader.Context().Done(): // El panel se desconecto
+			return
+
+		case event := <-events:
+			if !send(event) {
+				return
+			}
+
+		case <-ticker.C:
+			status := server.Status()
+			if !send(models.Event{Type: models.EventStats, Time: time.Now(), Status: &status}) {
+				return
+			}
+		}
+	}
+}
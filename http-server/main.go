@@ -1,152 +1,323 @@
-package main
-
-import (
-	"log"
-	"net/http"
-
-	"github.com/gorilla/mux"
-	"github.com/gorilla/websocket"
-	"github.com/kelseyhightower/envconfig"
-	"github.com/pipeduque/go-server/models"
-	"github.com/pipeduque/go-server/tcpServer"
-	"github.com/urfave/negroni"
-)
-
-var (
-	config   configuration
-	upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-
-		// Chequeamos el origen de la solicitud, en este caso por trabajar en localhost se acepta simplemente
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-	}
-)
-
-var server = models.NewServer()    //Establecemos el servidor para administrar el servicio
-var tcp = tcpServer.NewTcpServer() // Establecemos el protocolo
-
-type configuration struct {
-	Debug         bool   `default:"true"`
-	Scheme        string `default:"HTTP"`
-	ListenAddress string `default:":8080"`
-}
-
-// Endpoint, necesitamos nuestro enrutador de respuesta y nuestro objeto de solicitud
-func endpoint(writer http.ResponseWriter, reader *http.Request) {
-
-	connection, err := upgrader.Upgrade(writer, reader, nil)
-	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	defer connection.Close()
-
-	// Bucle de escucha
-	for {
-		messageType, request, err := connection.ReadMessage()
-
-		log.Println(string(request))
-
-		if err != nil {
-			http.Error(writer, err.Error(), http.StatusInternalServerError)
-			break
-		}
-
-		if messageType != websocket.TextMessage {
-			log.Fatal("Only text message are supported")
-			break
-		}
-
-		log.Println(server.ServerOn)
-		switch string(request) {
-		case "serverTcpOn":
-			if !server.ServerOn {
-				server.ServerOn = true
-				go startServerTcp(connection, writer, messageType)
-				go tcp.Run(server)
-				go server.Run() //corremos el servidor
-			} else {
-				server.ReqAndRes = "Server is on"
-			}
-
-		case "serverTcpOff":
-			if server.ServerOn {
-				server.ServerOn = false
-				tcp.StopTcp()
-				server.ReqAndRes = "Server off"
-			}
-		}
-	}
-}
-
-func startServerTcp(connection *websocket.Conn, writer http.ResponseWriter, messageType int) {
-
-	for server.ServerOn {
-		response := server.ReqAndRes
-
-		if response != "" {
-
-			if err := connection.WriteMessage(messageType, []byte(response)); err != nil {
-				log.Println(err)
-				return
-			}
-			server.ReqAndRes = ""
-		}
-	}
-}
-
-/* Funcion
- * Nombre: main
- * Descripcion: Iniciamos nuestra */
-func main() {
-
-	// Variables de entorno para los ajustes de configuración
-	err := envconfig.Process("SOCKETCAM", &config)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-
-	// Enrutador
-	router := newRouter()
-	n := negroni.Classic()
-
-	n.UseHandler(router)
-
-	// Informamos el inicio del servidor
-	if config.Debug {
-		log.Printf("==> PROTOCOL: %v", config.Scheme)
-		log.Printf("==> ADDRESS: %v", config.ListenAddress)
-	}
-
-	// Dejamos al servidor escuchando en el puerto 8080 e informamos en caso de error
-	log.Fatal(http.ListenAndServe(":8080", n))
-
-}
-
-/* Funcion
- * Nombre: newRouter
- * Descripcion: Constructor para todas las rutas */
-func newRouter() *mux.Router {
-
-	router := mux.NewRouter().StrictSlash(true)
-
-	// Ruta de comunicacion cliente - servidor
-	router.
-		Methods("GET").
-		Path("/ws").
-		Name("Communication Channel").
-		HandlerFunc(endpoint)
-
-	// Ruta para enviar contenido al navegador
-	router.
-		Methods("GET").
-		PathPrefix("/").
-		Name("Static").
-		Handler(http.FileServer(http.Dir("../static")))
-
-	return router
-}
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pipeduque/go-server/models"
+	"github.com/pipeduque/go-server/tcpServer"
+	"github.com/pipeduque/go-server/webhook"
+	"github.com/urfave/negroni"
+)
+
+var (
+	config   configuration
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+
+		// Chequeamos el origen de la solicitud, solo se acepta desde el host del panel
+		CheckOrigin: checkOrigin,
+	}
+	adminToken string // Token de la administracion, se carga en main
+)
+
+var server = models.NewServer()    //Establecemos el servidor para administrar el servicio
+var logger = server.Logger         // Registro del servidor HTTP, se reemplaza segun la configuracion en main
+var tcp = tcpServer.NewTcpServer() // Establecemos el protocolo
+var hooks *webhook.Dispatcher      // Webhooks de los canales, se crea en main con la configuracion
+
+type configuration struct {
+	Debug         bool          `default:"true"`
+	Scheme        string        `default:"HTTP"`
+	ListenAddress string        `default:":8080"`
+	HealthTimeout time.Duration `default:"1s"` // Tiempo maximo de cada comprobacion de /readyz
+	AdminToken    string        // Token de la consola y la API de administracion, si falta se genera uno al iniciar
+	Host          string        // Host publico del panel, como chat.example.com:8080, vacio usa el Host de la solicitud
+
+	WebhookAttempts      int           `default:"5"`   // Intentos de cada entrega de un webhook
+	WebhookRetryDelay    time.Duration `default:"1s"`  // Espera antes del primer reintento, se duplica en cada fallo
+	WebhookMaxRetryDelay time.Duration `default:"1m"`  // Espera maxima entre reintentos
+	WebhookTimeout       time.Duration `default:"10s"` // Tiempo maximo de cada intento
+	WebhookDeadLetters   int           `default:"100"` // Entregas fallidas que se guardan
+	WebhookAllowedHosts  []string      // Destinos permitidos de los webhooks, como host o host:puerto
+
+	models.Config // Limites del servidor TCP, SOCKETCAM_COMMANDRATE, SOCKETCAM_MAXCONNSPERIP, etc.
+}
+
+// Conexion websocket de la consola. Las respuestas del servidor TCP y de los comandos de administracion
+// se escriben desde distintas rutinas, y el websocket solo admite un escritor a la vez
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+/* Funcion
+ * Nombre: write
+ * Descripcion: Escribe un mensaje en el websocket, de a un escritor a la vez */
+func (connection *wsConn) write(messageType int, data []byte) error {
+
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+
+	return connection.WriteMessage(messageType, data)
+}
+
+// Endpoint, necesitamos nuestro enrutador de respuesta y nuestro objeto de solicitud
+func endpoint(writer http.ResponseWriter, reader *http.Request) {
+
+	wsLogger := logger.With("remote", reader.RemoteAddr)
+
+	conn, err := upgrader.Upgrade(writer, reader, nil)
+	if err != nil { // Upgrade ya respondio al navegador con el error
+		wsLogger.Warn("websocket upgrade failed", "error", err)
+		return
+	}
+
+	connection := &wsConn{Conn: conn}
+
+	defer connection.Close()
+
+	// Bucle de escucha
+	for {
+		messageType, request, err := connection.ReadMessage()
+
+		if err != nil { // La conexion se cerro, la respuesta HTTP ya fue reemplazada por el websocket
+			wsLogger.Debug("websocket closed", "error", err)
+			break
+		}
+
+		if messageType != websocket.TextMessage { // Cerramos la conexion sin terminar el proceso
+			wsLogger.Warn("websocket message rejected", "error", "only text messages are supported")
+			break
+		}
+
+		wsLogger.Debug("websocket request", "request", string(request), "server_on", server.ServerOn)
+		switch string(request) {
+		case "serverTcpOn":
+			if !server.ServerOn {
+				server.ServerOn = true
+				go startServerTcp(connection, writer, messageType)
+				go tcp.Run(server)
+				go server.Run() //corremos el servidor
+			} else {
+				server.SetResponse("Server is on")
+			}
+
+		case "serverTcpOff":
+			if server.ServerOn {
+				server.Stop()
+				tcp.StopTcp()
+				server.SetResponse("Server off")
+			}
+
+		default: // Comandos de administracion en JSON: {"action": "kick", "target": "..."}
+			if len(request) > 0 && request[0] == '{' {
+				wsLogger.Info("admin command", "request", string(request))
+
+				if err := connection.write(websocket.TextMessage, adminMessage(request)); err != nil {
+					wsLogger.Warn("websocket write failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+func startServerTcp(connection *wsConn, writer http.ResponseWriter, messageType int) {
+
+	for server.ServerOn {
+
+		if response := server.TakeResponse(); response != "" {
+
+			if err := connection.write(messageType, []byte(response)); err != nil {
+				logger.Warn("websocket write failed", "error", err)
+				return
+			}
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: healthz
+ * Descripcion: Responde que el proceso esta vivo, no depende del estado del servidor TCP */
+func healthz(writer http.ResponseWriter, reader *http.Request) {
+
+	writeHealth(writer, http.StatusOK, map[string]string{"process": "ok"})
+}
+
+/* Funcion
+ * Nombre: readyz
+ * Descripcion: Responde si el servicio puede atender clientes: el oyente TCP esta encendido, los canales
+ * estan disponibles y la rutina principal del servidor procesa comandos. Si alguna comprobacion falla responde 503 */
+func readyz(writer http.ResponseWriter, reader *http.Request) {
+
+	checks := map[string]string{"tcp": "ok", "storage": "ok", "event_loop": "ok"}
+	status := http.StatusOK
+
+	if !server.ServerOn || !tcp.Listening() { // Manejamos que el oyente TCP este aceptando conexiones
+		checks["tcp"] = "tcp listener is down"
+		status = http.StatusServiceUnavailable
+	}
+
+	// Las comprobaciones corren a la vez, asi la respuesta tarda como maximo HealthTimeout
+	storage, eventLoop := make(chan error, 1), make(chan error, 1)
+	go func() { storage <- server.CheckStorage(config.HealthTimeout) }()
+	go func() { eventLoop <- server.CheckEventLoop(config.HealthTimeout) }()
+
+	if err := <-storage; err != nil { // Manejamos que los canales esten disponibles
+		checks["storage"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	if err := <-eventLoop; err != nil { // Manejamos que la rutina principal responda
+		checks["event_loop"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	writeHealth(writer, status, checks)
+}
+
+/* Funcion
+ * Nombre: writeHealth
+ * Descripcion: Escribe el resultado de las comprobaciones de salud en JSON
+ * @status: codigo HTTP de la respuesta
+ * @checks: resultado de cada comprobacion, "ok" si fue exitosa */
+func writeHealth(writer http.ResponseWriter, status int, checks map[string]string) {
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "unavailable"
+	}
+
+	writeJSON(writer, status, map[string]interface{}{"status": result, "checks": checks})
+}
+
+/* Funcion
+ * Nombre: metrics
+ * Descripcion: Responde las metricas del servidor TCP en el formato de texto de Prometheus */
+func metrics(writer http.ResponseWriter, reader *http.Request) {
+
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if err := server.WriteMetrics(writer); err != nil {
+		logger.Warn("metrics write failed", "error", err)
+	}
+}
+
+/* Funcion
+ * Nombre: logRequest
+ * Descripcion: Registra cada solicitud HTTP con su estado y duracion */
+func logRequest(writer http.ResponseWriter, reader *http.Request, next http.HandlerFunc) {
+
+	start := time.Now()
+	next(writer, reader)
+
+	response := writer.(negroni.ResponseWriter)
+	logger.Debug("http request", "method", reader.Method, "path", reader.URL.Path, "status", response.Status(), "duration", time.Since(start), "remote", reader.RemoteAddr)
+}
+
+/* Funcion
+ * Nombre: main
+ * Descripcion: Iniciamos nuestra */
+func main() {
+
+	// Variables de entorno para los ajustes de configuración
+	err := envconfig.Process("SOCKETCAM", &config)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	server.Config = config.Config // Aplicamos los limites configurados al servidor
+
+	// Registro estructurado segun SOCKETCAM_LOGLEVEL y SOCKETCAM_LOGFORMAT
+	server.Logger, err = models.NewLogger(os.Stderr, config.LogLevel, config.LogFormat)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	logger = server.Logger.With("component", "http")
+
+	if adminToken, err = loadAdminToken(); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	hooks = webhook.NewDispatcher(server, webhook.Options{
+		Attempts:      config.WebhookAttempts,
+		RetryDelay:    config.WebhookRetryDelay,
+		MaxRetryDelay: config.WebhookMaxRetryDelay,
+		Timeout:       config.WebhookTimeout,
+		DeadLetters:   config.WebhookDeadLetters,
+		AllowedHosts:  config.WebhookAllowedHosts,
+	}, server.Logger)
+
+	// Enrutador
+	router := newRouter()
+	n := negroni.New(negroni.NewRecovery(), negroni.HandlerFunc(logRequest))
+
+	n.UseHandler(router)
+
+	// Informamos el inicio del servidor
+	if config.Debug {
+		logger.Info("http server started", "scheme", config.Scheme, "address", config.ListenAddress)
+	}
+
+	// Dejamos al servidor escuchando en el puerto 8080 e informamos en caso de error
+	if err := http.ListenAndServe(":8080", n); err != nil {
+		logger.Error("http server stopped", "error", err)
+		os.Exit(1)
+	}
+
+}
+
+/* Funcion
+ * Nombre: newRouter
+ * Descripcion: Constructor para todas las rutas */
+func newRouter() *mux.Router {
+
+	router := mux.NewRouter().StrictSlash(true)
+
+	// Ruta de comunicacion de la consola con el servidor, enciende el servidor TCP y ejecuta comandos de administracion
+	router.
+		Methods("GET").
+		Path("/ws").
+		Name("Communication Channel").
+		Handler(requireAdmin(http.HandlerFunc(endpoint)))
+
+	// Rutas de salud para el orquestador: proceso vivo y servicio listo
+	router.
+		Methods("GET").
+		Path("/healthz").
+		Name("Health").
+		HandlerFunc(healthz)
+
+	router.
+		Methods("GET").
+		Path("/readyz").
+		Name("Readiness").
+		HandlerFunc(readyz)
+
+	// Ruta de metricas del servidor en formato Prometheus
+	router.
+		Methods("GET").
+		Path("/metrics").
+		Name("Metrics").
+		HandlerFunc(metrics)
+
+	// Rutas de la API REST, de administracion y de eventos del panel
+	apiRoutes(router)
+	adminRoutes(router)
+	eventRoutes(router)
+
+	// Ruta para enviar contenido al navegador
+	router.
+		Methods("GET").
+		PathPrefix("/").
+		Name("Static").
+		Handler(http.FileServer(http.Dir("../static")))
+
+	return router
+}
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errores de los comandos de administracion
+var (
+	ErrClientNotFound  = errors.New("client not found")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// Tipos de baneo
+const (
+	BanIP   = "ip"   // Se rechazan las conexiones de la IP
+	BanUser = "user" // Se rechaza el registro del nombre
+)
+
+// Estructura con los datos de un baneo vigente
+type BanInfo struct {
+	Kind   string    `json:"kind"`
+	Target string    `json:"target"`
+	Until  time.Time `json:"until"`
+}
+
+/* Funcion
+ * Nombre: findTarget
+ * Descripcion: Busca los clientes conectados que corresponden a un nombre registrado o a una direccion ip:puerto
+ * @target: nombre o direccion del cliente */
+func (server *Server) findTarget(target string) []*Client {
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	if client, ok := server.nicknames[target]; ok {
+		return []*Client{client}
+	}
+
+	for address, client := range server.clients {
+		if address.String() == target {
+			return []*Client{client}
+		}
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: clientsFrom
+ * Descripcion: Retorna los clientes conectados desde una IP */
+func (server *Server) clientsFrom(ip string) []*Client {
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	clients := make([]*Client, 0)
+
+	for address, client := range server.clients {
+		if HostOf(address) == ip {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+/* Funcion
+ * Nombre: Kick
+ * Descripcion: Desconecta a un cliente del servidor informandole la razon
+ * @target: nombre registrado o direccion ip:puerto del cliente
+ * @reason: razon opcional
+ * return: @error: ErrClientNotFound si el cliente no esta conectado */
+func (server *Server) Kick(target string, reason string) error {
+
+	clients := server.findTarget(target)
+
+	if len(clients) == 0 {
+		return ErrClientNotFound
+	}
+
+	for _, client := range clients {
+		client.disconnect(errors.New(withReason("kicked by admin", reason)))
+	}
+
+	server.Logger.Info("client kicked", "component", "admin", "target", target, "reason", reason)
+	return nil
+}
+
+/* Funcion
+ * Nombre: Ban
+ * Descripcion: Banea una IP o un nombre registrado por un tiempo y desconecta a los clientes que correspondan.
+ * Una IP baneada no puede conectarse y un nombre baneado no puede registrarse
+ * @kind: BanIP o BanUser
+ * @target: IP o nombre a banear
+ * @duration: duracion del baneo, la configurada en BanDuration si es cero
+ * @reason: razon opcional
+ * return: @BanInfo: baneo creado
+ *         @error: ErrInvalidArgument si el tipo, la IP o la duracion no son validos */
+func (server *Server) Ban(kind string, target string, duration time.Duration, reason string) (BanInfo, error) {
+
+	if duration == 0 {
+		duration = server.Config.BanDuration
+	}
+
+	if duration <= 0 {
+		return BanInfo{}, fmt.Errorf("%w: ban duration must be positive", ErrInvalidArgument)
+	}
+
+	ban := BanInfo{Kind: kind, Target: target, Until: time.Now().Add(duration)}
+	var clients []*Client
+
+	switch kind {
+
+	case BanIP:
+		if net.ParseIP(target) == nil { // Manejamos que la IP sea valida
+			return BanInfo{}, fmt.Errorf("%w: invalid ip %q", ErrInvalidArgument, target)
+		}
+		server.limiter.ban(target, ban.Until)
+		clients = server.clientsFrom(target)
+
+	case BanUser:
+		if target == "" {
+			return BanInfo{}, fmt.Errorf("%w: empty nickname", ErrInvalidArgument)
+		}
+		server.mu.Lock()
+		server.userBans[target] = ban.Until
+		server.mu.Unlock()
+		clients = server.findTarget(target)
+
+	default:
+		return BanInfo{}, fmt.Errorf("%w: unknown ban kind %q", ErrInvalidArgument, kind)
+	}
+
+	for _, client := range clients { // Desconectamos a los clientes baneados
+		client.disconnect(errors.New(withReason("banned by admin for "+duration.String(), reason)))
+	}
+
+	server.Logger.Info("ban created", "component", "admin", "kind", kind, "target", target, "duration", duration, "reason", reason)
+	return ban, nil
+}
+
+/* Funcion
+ * Nombre: Bans
+ * Descripcion: Retorna los baneos vigentes de IPs y nombres ordenados por su vencimiento */
+func (server *Server) Bans() []BanInfo {
+
+	now := time.Now()
+	bans := server.limiter.bans(now)
+
+	server.mu.Lock()
+	for nickname, until := range server.userBans {
+		if now.Before(until) {
+			bans = append(bans, BanInfo{Kind: BanUser, Target: nickname, Until: until})
+		} else {
+			delete(server.userBans, nickname) // Descartamos los baneos vencidos
+		}
+	}
+	server.mu.Unlock()
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+/* Funcion
+ * Nombre: userBanned
+ * Descripcion: Indica si un nombre esta baneado, puede llamarse desde cualquier rutina */
+func (server *Server) userBanned(nickname string) bool {
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	until, ok := server.userBans[nickname]
+	return ok && time.Now().Before(until)
+}
+
+/* Funcion
+ * Nombre: DeleteChannel
+ * Descripcion: Elimina un canal con sus mensajes e informa a sus miembros
+ * @channelName: nombre del canal
+ * return: @error: ErrChannelNotFound si el canal no existe */
+func (server *Server) DeleteChannel(channelName string) error {
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	channel, ok := server.channel(channelName)
+
+	if !ok {
+		return ErrChannelNotFound
+	}
+
+	delete(shard.channels, channelKey(channelName))
+	server.deleteIntegrations(channel.name)
+	server.metrics.forget(channel.name)
+	channel.broadcast("NOTICE channel " + channel.name + " was deleted by admin")
+	server.channelEvent(EventChannelDeleted, channel)
+
+	server.Logger.Info("channel deleted", "component", "admin", "channel", channel.name)
+	return nil
+}
+
+/* Funcion
+ * Nombre: DeleteMessage
+ * Descripcion: Elimina un mensaje del historial de un canal, dejando su marca de eliminado como el comando DELETE
+ * @channelName: nombre del canal
+ * @id: identificador del mensaje
+ * return: @error: ErrChannelNotFound o ErrMessageNotFound si no existen o el mensaje ya se elimino */
+func (server *Server) DeleteMessage(channelName string, id uint64) error {
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	channel, ok := server.channel(channelName)
+
+	if !ok {
+		return ErrChannelNotFound
+	}
+
+	message := channel.findMessage(id)
+
+	if message == nil || message.isDeleted() {
+		return ErrMessageNotFound
+	}
+	server.removeMessage(channel, message)
+
+	server.Logger.Info("message deleted", "component", "admin", "channel", channel.name, "message", id)
+	return nil
+}
+
+/* Funcion
+ * Nombre: Broadcast
+ * Descripcion: Envia un aviso del servidor a todos los clientes conectados como NOTICE [texto]
+ * @text: texto del aviso, en una sola linea
+ * return: @int: clientes que recibieron el aviso
+ *         @error: ErrInvalidArgument si el texto es vacio o tiene saltos de linea */
+func (server *Server) Broadcast(text string) (int, error) {
+
+	if strings.TrimSpace(text) == "" || strings.ContainsAny(text, "\r\n") {
+		return 0, fmt.Errorf("%w: notice must be a single non empty line", ErrInvalidArgument)
+	}
+
+	server.mu.RLock()
+	clients := make([]*Client, 0, len(server.clients))
+	for _, client := range server.clients {
+		clients = append(clients, client)
+	}
+	server.mu.RUnlock()
+
+	for _, client := range clients {
+		client.WriteResponse("NOTICE " + text)
+	}
+
+	server.Logger.Info("notice broadcast", "component", "admin", "clients", len(clients), "text", text)
+	return len(clients), nil
+}
+
+/* Funcion
+ * Nombre: withReason
+ * Descripcion: Agrega la razon opcional a un mensaje */
+func withReason(message string, reason string) string {
+
+	if reason == "" {
+		return message
+	}
+	return message + ": " + reason
+}
@@ -0,0 +1,431 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Errores de las consultas de la API, el servidor HTTP los traduce a su codigo de estado
+var (
+	ErrChannelNotFound = errors.New("channel not found")
+	ErrInvalidMessage  = errors.New("invalid message")
+)
+
+// Cantidad de mensajes por pagina del historial si no se indica, y el maximo permitido
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// Estructura con los datos publicos de un canal
+type ChannelInfo struct {
+	Name        string    `json:"name"`
+	Topic       string    `json:"topic"`
+	Description string    `json:"description"`
+	Creator     string    `json:"creator"`
+	Modes       string    `json:"modes"`
+	Members     int       `json:"members"`
+	Messages    int       `json:"messages"`
+	Created     time.Time `json:"created"`
+	Activity    time.Time `json:"activity"`
+}
+
+// Estructura con los datos publicos de un mensaje
+type MessageInfo struct {
+	ID        uint64         `json:"id"`
+	Date      time.Time      `json:"date"`
+	Author    string         `json:"author"`
+	Content   string         `json:"content"`
+	File      string         `json:"file,omitempty"`
+	Edited    *time.Time     `json:"edited,omitempty"`    // Fecha de la ultima edicion
+	Edits     []Revision     `json:"edits,omitempty"`     // Contenidos anteriores, del mas antiguo al mas reciente
+	Deleted   *time.Time     `json:"deleted,omitempty"`   // Fecha de eliminacion, el mensaje queda sin contenido
+	Parent    uint64         `json:"parent,omitempty"`    // Mensaje raiz del hilo de una respuesta
+	Replies   int            `json:"replies,omitempty"`   // Respuestas del hilo de un mensaje raiz
+	Reactions []ReactionInfo `json:"reactions,omitempty"` // Reacciones en el orden en que se usaron
+}
+
+// Estructura con una pagina del historial de un canal, del mensaje mas antiguo al mas reciente
+type MessagePage struct {
+	Messages []MessageInfo `json:"messages"`
+	Next     uint64        `json:"next,omitempty"` // Valor de before para la pagina anterior, cero si no hay mas mensajes
+}
+
+// Estructura con los datos publicos de un cliente conectado
+type ClientInfo struct {
+	Conn     uint64    `json:"conn,omitempty"`    // Solo para los administradores
+	Address  string    `json:"address,omitempty"` // Solo para los administradores
+	Nickname string    `json:"nickname,omitempty"`
+	LastSeen time.Time `json:"lastSeen"`
+	Queue    int       `json:"queue,omitempty"` // Solo para los administradores
+}
+
+// Estructura con el estado del servidor
+type Status struct {
+	ServerOn   bool       `json:"serverOn"`
+	Started    time.Time  `json:"started"`
+	Uptime     string     `json:"uptime"`
+	Clients    int        `json:"clients"`
+	Registered int        `json:"registered"`
+	Channels   int        `json:"channels"`
+	Messages   int        `json:"messages"`
+	Queue      QueueStats `json:"queue"`
+	Totals     Totals     `json:"totals"`
+}
+
+// Estructura con los contadores acumulados del servidor, el panel calcula el rendimiento con su variacion
+type Totals struct {
+	Commands      int64 `json:"commands"`
+	Messages      int64 `json:"messages"`
+	Errors        int64 `json:"errors"`
+	ReceivedBytes int64 `json:"receivedBytes"`
+	SentBytes     int64 `json:"sentBytes"`
+}
+
+/* Funcion
+ * Nombre: info
+ * Descripcion: Retorna los datos publicos del canal, debe llamarse con la particion del canal bloqueada */
+func (channel *Channel) info() ChannelInfo {
+
+	return ChannelInfo{
+		Name:        channel.name,
+		Topic:       channel.topic,
+		Description: channel.desc,
+		Creator:     channel.creator,
+		Modes:       channel.modes(),
+		Members:     len(channel.clients),
+		Messages:    len(channel.messages),
+		Created:     channel.date,
+		Activity:    channel.activity,
+	}
+}
+
+/* Funcion
+ * Nombre: info
+ * Descripcion: Retorna los datos publicos del mensaje */
+func (message *Message) info() MessageInfo {
+
+	info := MessageInfo{
+		ID:        message.id,
+		Date:      message.date,
+		Author:    message.author,
+		Content:   string(message.content),
+		File:      string(message.file),
+		Edits:     append([]Revision(nil), message.edits...),
+		Parent:    message.parent,
+		Replies:   message.replyCount(),
+		Reactions: message.reactionInfo(),
+	}
+
+	if !message.edited.IsZero() {
+		edited := message.edited
+		info.Edited = &edited
+	}
+
+	if message.isDeleted() {
+		deleted := message.deleted
+		info.Deleted = &deleted
+	}
+	return info
+}
+
+/* Funcion
+ * Nombre: info
+ * Descripcion: Retorna los datos publicos del cliente */
+func (client *Client) info() ClientInfo {
+
+	client.mu.Lock()
+	nickname := client.nickname
+	client.mu.Unlock()
+
+	return ClientInfo{
+		Conn:     client.id,
+		Address:  client.address.String(),
+		Nickname: nickname,
+		LastSeen: client.seen(),
+		Queue:    client.QueueDepth(),
+	}
+}
+
+/* Funcion
+ * Nombre: visibleChannel
+ * Descripcion: Busca un canal para una consulta de la API. La API no tiene la identidad de un cliente, asi que
+ * los canales con modos solo los ven los administradores; para los demas no existen, como en LIST_CHN.
+ * Debe llamarse con la particion del canal bloqueada
+ * @channelName: nombre del canal
+ * @admin: true si la consulta viene de un administrador
+ * return: @*Channel: canal encontrado
+ *         @bool: true si el canal existe y es visible */
+func (server *Server) visibleChannel(channelName string, admin bool) (*Channel, bool) {
+
+	channel, ok := server.channel(channelName)
+
+	if !ok || (!admin && channel.restricted()) {
+		return nil, false
+	}
+	return channel, true
+}
+
+/* Funcion
+ * Nombre: Channels
+ * Descripcion: Retorna los datos de los canales del servidor ordenados por fecha de creacion
+ * @admin: true para incluir los canales con modos */
+func (server *Server) Channels(admin bool) []ChannelInfo {
+
+	channels := make([]ChannelInfo, 0)
+
+	for _, shard := range server.shards {
+
+		shard.mu.RLock()
+		for _, channel := range shard.channels {
+			if admin || !channel.restricted() {
+				channels = append(channels, channel.info())
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Created.Before(channels[j].Created) })
+	return channels
+}
+
+/* Funcion
+ * Nombre: Channel
+ * Descripcion: Retorna los datos de un canal
+ * @channelName: nombre del canal
+ * @admin: true si la consulta viene de un administrador
+ * return: @ChannelInfo: datos del canal
+ *         @error: ErrChannelNotFound si el canal no existe o no es visible */
+func (server *Server) Channel(channelName string, admin bool) (ChannelInfo, error) {
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	channel, ok := server.visibleChannel(channelName, admin)
+
+	if !ok {
+		return ChannelInfo{}, ErrChannelNotFound
+	}
+	return channel.info(), nil
+}
+
+/* Funcion
+ * Nombre: Messages
+ * Descripcion: Retorna una pagina del historial de un canal con los mensajes anteriores a before
+ * @channelName: nombre del canal
+ * @before: identificador desde el que se pagina hacia atras, cero para empezar por el mensaje mas reciente
+ * @limit: cantidad maxima de mensajes, entre 1 y MaxPageSize
+ * @admin: true si la consulta viene de un administrador
+ * return: @MessagePage: pagina del historial
+ *         @error: ErrChannelNotFound si el canal no existe o no es visible */
+func (server *Server) Messages(channelName string, before uint64, limit int, admin bool) (MessagePage, error) {
+
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	channel, ok := server.visibleChannel(channelName, admin)
+
+	if !ok {
+		return MessagePage{}, ErrChannelNotFound
+	}
+
+	end := len(channel.messages) // Los identificadores son crecientes, buscamos el primer mensaje que no entra en la pagina
+
+	if before > 0 {
+		end = sort.Search(len(channel.messages), func(i int) bool { return channel.messages[i].id >= before })
+	}
+
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	page := MessagePage{Messages: make([]MessageInfo, 0, end-start)}
+
+	for _, message := range channel.messages[start:end] {
+		page.Messages = append(page.Messages, message.info())
+	}
+
+	if start > 0 { // Quedan mensajes mas antiguos
+		page.Next = channel.messages[start].id
+	}
+	return page, nil
+}
+
+/* Funcion
+ * Nombre: PostMessage
+ * Descripcion: Publica un mensaje del propio servidor en un canal, por el mismo camino que un MSG de un cliente.
+ * El autor debe usar un prefijo reservado, asi ningun cliente puede tener su nombre ni modificar sus mensajes
+ * @channelName: nombre del canal
+ * @author: nombre con el que se publica el mensaje, con SystemPrefix o AdminPrefix
+ * @content: contenido del mensaje
+ * @file: base64 opcional de un archivo
+ * return: @MessageInfo: mensaje publicado
+ *         @error: ErrChannelNotFound si el canal no existe, ErrInvalidMessage si el mensaje no es valido */
+func (server *Server) PostMessage(channelName string, author string, content []byte, file []byte) (MessageInfo, error) {
+
+	if err := validateMessage(author, content, file, server.Config.MaxRequestSize); err != nil {
+		return MessageInfo{}, err
+	}
+
+	if prefix, ok := reservedPrefix(author); !ok || len(author) == len(prefix) { // Manejamos que el autor sea una identidad del servidor
+		return MessageInfo{}, fmt.Errorf("%w: author %q must be %s or %s followed by a name", ErrInvalidMessage, author, SystemPrefix, AdminPrefix)
+	}
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	message := server.sendMessage(systemAddr{author}, channelName, content, file, 0)
+
+	if message == nil {
+		return MessageInfo{}, ErrChannelNotFound
+	}
+	return message.info(), nil
+}
+
+/* Funcion
+ * Nombre: validateMessage
+ * Descripcion: Valida un mensaje publicado fuera del protocolo TCP, con las mismas reglas que una solicitud MSG
+ * @maxSize: tamaño maximo de una solicitud, cero si no hay limite
+ * return: @error: ErrInvalidMessage con la razon si no es valido */
+func validateMessage(author string, content []byte, file []byte, maxSize int) error {
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidMessage, reason)
+	}
+
+	if author == "" || bytes.ContainsAny([]byte(author), " \t,;\n") { // Igual que los nombres registrados con REG
+		return invalid("invalid author " + strconv.Quote(author))
+	}
+
+	if len(content) == 0 {
+		return invalid("empty content")
+	}
+
+	if bytes.ContainsAny(content, "\r\n") || bytes.ContainsAny(file, "\r\n") { // Las respuestas del protocolo son lineas
+		return invalid("content must be a single line")
+	}
+
+	if maxSize > 0 && len(content)+len(file) > maxSize {
+		return invalid("larger than " + strconv.Itoa(maxSize) + " bytes")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: Members
+ * Descripcion: Retorna los clientes miembros de un canal ordenados por su nombre. Solo los administradores ven
+ * la conexion y la direccion de cada miembro, los demas solo su nombre y su ultima actividad
+ * @channelName: nombre del canal
+ * @admin: true si la consulta viene de un administrador
+ * return: @[]ClientInfo: miembros del canal
+ *         @error: ErrChannelNotFound si el canal no existe o no es visible */
+func (server *Server) Members(channelName string, admin bool) ([]ClientInfo, error) {
+
+	shard := shardFor(server.shards, channelName)
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	channel, ok := server.visibleChannel(channelName, admin)
+
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
+
+	members := make([]ClientInfo, 0, len(channel.clients))
+
+	for client := range channel.clients {
+		members = append(members, client.info())
+	}
+
+	sortClients(members)
+
+	if !admin { // La direccion de los clientes no es publica
+		for i := range members {
+			members[i] = ClientInfo{Nickname: members[i].Nickname, LastSeen: members[i].LastSeen}
+		}
+	}
+	return members, nil
+}
+
+/* Funcion
+ * Nombre: Clients
+ * Descripcion: Retorna los clientes conectados al servidor ordenados por su nombre */
+func (server *Server) Clients() []ClientInfo {
+
+	server.mu.RLock()
+
+	clients := make([]ClientInfo, 0, len(server.clients))
+
+	for _, client := range server.clients {
+		clients = append(clients, client.info())
+	}
+
+	server.mu.RUnlock()
+
+	sortClients(clients)
+	return clients
+}
+
+/* Funcion
+ * Nombre: sortClients
+ * Descripcion: Ordena clientes por su nombre registrado y luego por el identificador de su conexion */
+func sortClients(clients []ClientInfo) {
+
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Nickname != clients[j].Nickname {
+			return clients[i].Nickname < clients[j].Nickname
+		}
+		return clients[i].Conn < clients[j].Conn
+	})
+}
+
+/* Funcion
+ * Nombre: Status
+ * Descripcion: Retorna el estado del servidor */
+func (server *Server) Status() Status {
+
+	server.mu.RLock()
+	clients := len(server.clients)
+	registered := len(server.nicknames)
+	server.mu.RUnlock()
+
+	status := Status{
+		ServerOn:   server.ServerOn,
+		Started:    server.started,
+		Uptime:     time.Since(server.started).Round(time.Second).String(),
+		Clients:    clients,
+		Registered: registered,
+		Queue:      server.QueueStats(),
+		Totals:     server.metrics.totals(),
+	}
+
+	for _, shard := range server.shards {
+
+		shard.mu.RLock()
+		status.Channels += len(shard.channels)
+		for _, channel := range shard.channels {
+			status.Messages += len(channel.messages)
+		}
+		shard.mu.RUnlock()
+	}
+	return status
+}
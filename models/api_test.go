@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+/* Funcion
+ * Nombre: TestMembersAddress
+ * Descripcion: Solo los administradores ven la conexion y la direccion de los miembros de un canal */
+func TestMembersAddress(t *testing.T) {
+
+	server := NewServer()
+	channel := NewChannel("general", "ana")
+	channel.clients[testClient(5001, "ana")] = true
+	channel.clients[testClient(5002, "")] = true
+	shardFor(server.shards, "general").channels[channelKey("general")] = channel
+
+	public, err := server.Members("general", false)
+	if err != nil || len(public) != 2 {
+		t.Fatalf("Members = %v, %v", public, err)
+	}
+	for _, member := range public {
+		if member.Conn != 0 || member.Address != "" {
+			t.Errorf("public member %+v shows its connection", member)
+		}
+	}
+	if public[1].Nickname != "ana" || public[1].LastSeen.IsZero() {
+		t.Errorf("public member %+v, want nickname and last seen", public[1])
+	}
+
+	admin, _ := server.Members("general", true)
+	for _, member := range admin {
+		if member.Address == "" {
+			t.Errorf("admin member %+v without its address", member)
+		}
+	}
+}
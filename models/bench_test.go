@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Conexion de prueba al servidor, con las solicitudes etiquetadas para esperar su DONE
+type benchConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tags   uint64
+}
+
+/* Funcion
+ * Nombre: benchServer
+ * Descripcion: Inicia un servidor sin limites que acepta conexiones TCP en una direccion local, como tcpServer.
+ * Se usa un oyente real porque las conexiones de net.Pipe comparten la misma direccion
+ * return: @string: direccion del oyente */
+func benchServer(b *testing.B) string {
+
+	server := NewServer()
+	server.Logger, _ = NewLogger(io.Discard, "error", FormatLogfmt)
+	server.ServerOn = true
+	go server.Run()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			client := NewClient(connection, server)
+			server.ClientOnlineReq <- client
+			go client.RequestReadHandle()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+/* Funcion
+ * Nombre: dial
+ * Descripcion: Conecta un cliente al servidor de prueba y registra su nombre */
+func dial(b *testing.B, address string, nickname string) *benchConn {
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+
+	client := &benchConn{conn: conn, reader: bufio.NewReader(conn)}
+	client.do(b, "REG "+nickname)
+	return client
+}
+
+/* Funcion
+ * Nombre: do
+ * Descripcion: Envia una solicitud etiquetada y espera su DONE, descartando las demas lineas */
+func (client *benchConn) do(b *testing.B, request string) {
+
+	client.tags++
+	tag := strconv.FormatUint(client.tags, 10)
+
+	if _, err := client.conn.Write([]byte("@" + tag + " " + request + "\n")); err != nil {
+		b.Fatal(err)
+	}
+
+	for {
+		line, err := client.reader.ReadString('\n')
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, "@"+tag+" ERROR") {
+			b.Fatalf("%s: %s", request, line)
+		}
+		if line == "DONE "+tag {
+			return
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: BenchmarkMessage
+ * Descripcion: Mensajes por segundo de clientes en paralelo, cada uno en su propio canal, esperando la
+ * confirmacion de cada mensaje */
+func BenchmarkMessage(b *testing.B) {
+
+	address := benchServer(b)
+	var clients uint64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+
+		id := strconv.FormatUint(atomic.AddUint64(&clients, 1), 10)
+		client := dial(b, address, "bench"+id)
+		client.do(b, "CREATE bench"+id)
+		client.do(b, "JOIN bench"+id)
+
+		for pb.Next() {
+			client.do(b, "MSG bench"+id+";;hola, mundo")
+		}
+	})
+}
+
+/* Funcion
+ * Nombre: BenchmarkFanOut
+ * Descripcion: Entrega de cada mensaje de un canal a todos sus miembros, segun la cantidad de miembros */
+func BenchmarkFanOut(b *testing.B) {
+
+	for _, members := range []int{1, 10, 100} {
+
+		b.Run("members="+strconv.Itoa(members), func(b *testing.B) {
+
+			address := benchServer(b)
+
+			sender := dial(b, address, "sender")
+			sender.do(b, "CREATE fanout")
+			sender.do(b, "JOIN fanout")
+
+			received := make(chan struct{}, members)
+
+			for i := 0; i < members; i++ {
+
+				member := dial(b, address, "member"+strconv.Itoa(i))
+				member.do(b, "JOIN fanout")
+
+				go func() { // Cada miembro avisa cada MSG que recibe del canal
+					for {
+						line, err := member.reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if strings.HasPrefix(line, "MSG fanout ") {
+							received <- struct{}{}
+						}
+					}
+				}()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+
+			for i := 0; i < b.N; i++ {
+
+				sender.do(b, "MSG fanout;;hola, mundo")
+
+				for j := 0; j < members; j++ {
+					<-received
+				}
+			}
+
+			b.ReportMetric(float64(b.N*members)/time.Since(start).Seconds(), "deliveries/s")
+		})
+	}
+}
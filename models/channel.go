@@ -1,24 +1,192 @@
 package models
 
-import "time"
+import (
+	"sort"
+	"strconv"
+	"time"
+)
 
 // Estructura para la creacion de un canal
 type Channel struct {
-	name     string              // Nombre del canal
-	date     time.Time           // Fecha de creacion
-	clients  map[*Client]bool    // Clientes
-	messages map[string]*Message // Mensajes del canal
+	name       string           // Nombre del canal
+	date       time.Time        // Fecha de creacion
+	creator    string           // Nombre del cliente que creo el canal
+	topic      string           // Tema del canal
+	desc       string           // Descripcion del canal
+	activity   time.Time        // Fecha de la ultima actividad en el canal
+	clients    map[*Client]bool // Clientes
+	messages   []*Message       // Mensajes del canal en orden de llegada
+	lastID     uint64           // Identificador del ultimo mensaje del canal
+	operators  map[*Client]bool // Operadores del canal, pueden invitar y cambiar los modos
+	invites    map[string]bool  // Invitaciones pendientes, por nombre reclamado del cliente invitado
+	private    bool             // Modo privado: el canal no se lista a quien no es miembro
+	inviteOnly bool             // Modo solo invitacion: para entrar se requiere una invitacion
+	password   []byte           // Sal y hash de la contraseña del canal, nil si no esta protegido
 }
 
 /* Funcion
  * Nombre: NewChannel
  * Descripcion: Funcion encargada de crear un canal apartir de la estructura */
-func NewChannel(nameChannel string) *Channel {
+func NewChannel(nameChannel string, creator string) *Channel {
+
+	now := time.Now()
 
 	return &Channel{
-		name:     nameChannel,
-		date:     time.Now(),
-		clients:  make(map[*Client]bool),
-		messages: make(map[string]*Message),
+		name:      nameChannel,
+		date:      now,
+		creator:   creator,
+		activity:  now,
+		clients:   make(map[*Client]bool),
+		operators: make(map[*Client]bool),
+		invites:   make(map[string]bool),
+	}
+}
+
+/* Funcion
+ * Nombre: isVisibleTo
+ * Descripcion: Indica si el canal puede ser listado por el cliente dado
+ * @client: cliente que solicita el listado */
+func (channel *Channel) isVisibleTo(client *Client) bool {
+
+	return !channel.private || channel.clients[client] || channel.operators[client]
+}
+
+/* Funcion
+ * Nombre: restricted
+ * Descripcion: Indica si el canal tiene algun modo, entonces solo sus miembros ven y escriben sus mensajes */
+func (channel *Channel) restricted() bool {
+
+	return channel.private || channel.inviteOnly || channel.password != nil
+}
+
+/* Funcion
+ * Nombre: isAccessibleTo
+ * Descripcion: Indica si el cliente puede leer y escribir los mensajes del canal: cualquiera si el canal no
+ * tiene modos, o solo sus miembros y operadores si los tiene
+ * @client: cliente que solicita el canal */
+func (channel *Channel) isAccessibleTo(client *Client) bool {
+
+	return !channel.restricted() || channel.clients[client] || channel.operators[client]
+}
+
+/* Funcion
+ * Nombre: modes
+ * Descripcion: Retorna los modos activos del canal en formato +ipk */
+func (channel *Channel) modes() string {
+
+	modes := "+"
+
+	if channel.inviteOnly {
+		modes += "i"
+	}
+	if channel.private {
+		modes += "p"
+	}
+	if channel.password != nil {
+		modes += "k"
+	}
+	return modes
+}
+
+/* Funcion
+ * Nombre: addMessage
+ * Descripcion: Agrega un mensaje al final del historial del canal y le asigna el siguiente identificador
+ * @message: mensaje a agregar */
+func (channel *Channel) addMessage(message *Message) {
+
+	channel.lastID++
+	message.id = channel.lastID
+	channel.messages = append(channel.messages, message)
+	channel.touch()
+}
+
+/* Funcion
+ * Nombre: findMessage
+ * Descripcion: Busca un mensaje del historial del canal por su identificador
+ * @id: identificador del mensaje
+ * return: @*Message: mensaje, nil si no existe */
+func (channel *Channel) findMessage(id uint64) *Message {
+
+	i := sort.Search(len(channel.messages), func(i int) bool { return channel.messages[i].id >= id })
+
+	if i == len(channel.messages) || channel.messages[i].id != id {
+		return nil
+	}
+	return channel.messages[i]
+}
+
+/* Funcion
+ * Nombre: threadRoot
+ * Descripcion: Busca el mensaje raiz del hilo al que pertenece un mensaje. Responder a una respuesta agrega la
+ * respuesta al hilo de su raiz, los hilos no se anidan
+ * @id: identificador del mensaje
+ * return: @*Message: raiz del hilo, nil si el mensaje o su raiz no existen o se eliminaron */
+func (channel *Channel) threadRoot(id uint64) *Message {
+
+	message := channel.findMessage(id)
+
+	if message != nil && message.parent != 0 {
+		message = channel.findMessage(message.parent)
+	}
+
+	if message == nil || message.isDeleted() {
+		return nil
+	}
+	return message
+}
+
+/* Funcion
+ * Nombre: canModify
+ * Descripcion: Indica si un cliente puede editar o eliminar un mensaje del canal: su autor o un operador del canal.
+ * Un nombre sin contraseña lo puede registrar cualquiera cuando su dueño se desconecta, asi que el autor de un
+ * mensaje enviado con un nombre sin verificar solo lo modifica desde la misma conexion. Los mensajes de las
+ * integraciones y del propio servidor solo los modifican los operadores
+ * @client: cliente que solicita el cambio
+ * @message: mensaje a modificar */
+func (channel *Channel) canModify(client *Client, message *Message) bool {
+
+	if channel.operators[client] {
+		return true
+	}
+
+	switch message.sender.(type) {
+	case integrationAddr, systemAddr:
+		return false
+	}
+
+	if !message.verified {
+		return message.sender == client.address
+	}
+
+	nickname, verified := client.account()
+	return verified && message.author == nickname
+}
+
+/* Funcion
+ * Nombre: touch
+ * Descripcion: Actualiza la fecha de la ultima actividad del canal */
+func (channel *Channel) touch() {
+
+	channel.activity = time.Now()
+}
+
+/* Funcion
+ * Nombre: format
+ * Descripcion: Retorna los datos del canal en el formato del protocolo:
+ * fecha_creacion,nombre,tema,descripcion,creador,miembros,ultima_actividad, con el tema y la descripcion escapados */
+func (channel *Channel) format() string {
+
+	return channel.date.Format("2006-01-02:15:04:05") + "," + channel.name + "," + escapeField(channel.topic) + "," + escapeField(channel.desc) + "," +
+		channel.creator + "," + strconv.Itoa(len(channel.clients)) + "," + channel.activity.Format("2006-01-02:15:04:05")
+}
+
+/* Funcion
+ * Nombre: broadcast
+ * Descripcion: Envia una respuesta a todos los miembros del canal
+ * @res: respuesta a enviar */
+func (channel *Channel) broadcast(res string) {
+
+	for client := range channel.clients {
+		client.WriteResponse(res)
 	}
 }
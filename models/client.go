@@ -2,11 +2,14 @@ package models
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"io"
-	"log"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Estructura para la creacion de clientes
@@ -14,17 +17,65 @@ type Client struct {
 	address    net.Addr
 	connection net.Conn
 	middlemane chan<- Command
+	shards     []*shard        // Particiones de los canales del servidor, reciben los comandos sobre un canal
+	offline    chan<- *Client  // Canal para solicitar al servidor la desconexion del cliente
+	stopped    <-chan struct{} // Se cierra al apagar el servidor, nadie recibe sus comandos ni su desconexion
+	mu         sync.Mutex      // Protege el nombre del cliente, lo lee cualquier particion
+	nickname   string          // Nombre registrado por el cliente, vacio si no se ha registrado
+	verified   bool            // El nombre se registro con su contraseña, solo asi accede a sus mensajes directos
+	limiter    *limiter        // Limitador de solicitudes del servidor
+	limits     limits          // Limites propios del cliente
+	config     *Config         // Configuracion de los limites del servidor
+	reader     *bufio.Reader   // Lector de solicitudes de la conexion, se reutiliza en cada solicitud
+	lastSeen   int64           // Ultima vez que se recibio una solicitud del cliente, en nanosegundos unix
+	outbound   chan []byte     // Cola de salida de respuestas, la escribe responseWriteHandle
+	closing    chan struct{}   // Se cierra para que responseWriteHandle escriba la cola y cierre la conexion
+	closeOnce  sync.Once       // Cierra closing una sola vez
+	online     chan struct{}   // Se cierra cuando el servidor registra al cliente
+	done       chan struct{}   // Se cierra cuando termina la conexion
+	stats      *QueueStats     // Metricas de las colas de salida del servidor
+	metrics    *Metrics        // Metricas del servidor
+	id         uint64          // Identificador de la conexion, correlaciona las lineas del registro
+	logger     *Logger         // Registro del servidor con los campos de la conexion
+	tag        string          // Etiqueta de la solicitud en curso, la escribe la rutina de lectura antes de entregar el comando
+	dispatched bool            // La solicitud en curso se envio al servidor, que confirma su etiqueta
 }
 
+// Ultimo identificador de conexion asignado
+var connectionIDs uint64
+
 /* Funcion
  * Nombre: NewClient
  * Descripcion: Funcion encargada de crear nuevos clientes apartir de la estructura */
 func NewClient(connection net.Conn, server *Server) *Client {
 
+	id := atomic.AddUint64(&connectionIDs, 1)
+	queueSize := server.Config.QueueSize
+
+	if queueSize <= 0 { // Una cola sin espacio descartaria todas las respuestas
+		queueSize = DefaultQueueSize
+	}
+
 	return &Client{
 		address:    connection.RemoteAddr(),
 		connection: connection,
 		middlemane: server.commands,
+		shards:     server.shards,
+		offline:    server.clientOfflineReq,
+		stopped:    server.done(),
+		limiter:    server.limiter,
+		limits:     server.limiter.newClientLimits(),
+		config:     &server.Config,
+		reader:     bufio.NewReaderSize(connection, server.Config.MaxRequestSize),
+		lastSeen:   time.Now().UnixNano(),
+		outbound:   make(chan []byte, queueSize),
+		closing:    make(chan struct{}),
+		online:     make(chan struct{}),
+		done:       make(chan struct{}),
+		stats:      server.queueStats,
+		metrics:    server.metrics,
+		id:         id,
+		logger:     server.Logger.With("conn", id, "remote", connection.RemoteAddr().String()),
 	}
 }
 
@@ -35,22 +86,35 @@ func (client *Client) RequestReadHandle() {
 
 	connection := client.connection //Conexion perteniente al cliente con el servidor
 
+	go client.responseWriteHandle() // Las respuestas se escriben en su propia rutina para no bloquear al servidor
+
 	defer func() { //Funcion diferida que cerrara la conexion cada vez que handleRead termine
-		if err := connection.Close(); err != nil {
-			log.Println("Error closing connection: ", err)
+		connection.Close() // La conexion pudo cerrarse antes, por ejemplo al desconectar a un cliente lento
+
+		close(client.done) // Terminamos la rutina de escritura
+
+		select {
+		case client.offline <- client: // Desconectamos al cliente del servidor
+		case <-client.stopped: // El servidor se apago, Run ya no recibe desconexiones
 		}
 	}()
 
 	for { // Ciclo para estar escuchando las solicitudes del cliente hasta que el rompa la conexion
 
-		request, err := bufio.NewReader(connection).ReadBytes('\n') // buffer para leer las solicitudes entrantes del cliente. lector de solicitudes
+		request, err := client.readRequest() // Leemos la siguiente solicitud del cliente
 		//log.Println("Request: ", string(request))
 
+		if err == errRequestTooLarge { // Las solicitudes demasiado grandes se rechazan sin cerrar la conexion
+			client.writeError(errors.New("request larger than " + strconv.Itoa(client.config.MaxRequestSize) + " bytes"))
+			continue
+		}
+
 		if err != nil { //Manejamos un posible error en la solicitud
-			if err == io.EOF { //si el error es end-of-line (EOF) en el lector de solicitudes desconectamos el cliente
-				// Aqui se debe desconectar al usuario
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() { // El cliente supero el tiempo de espera
+				client.writeError(errors.New("timeout waiting for request"))
+			} else if err != io.EOF { //si el error es end-of-line (EOF) el cliente cerro la conexion, la funcion diferida lo desconecta
+				client.log().Warn("read failed", "error", err)
 			}
-			log.Println(err)
 			break
 		}
 
@@ -58,239 +122,711 @@ func (client *Client) RequestReadHandle() {
 	}
 }
 
+/* Funcion
+ * Nombre: readRequest
+ * Descripcion: Lee la siguiente solicitud del cliente, hasta el salto de linea. Espera la solicitud como maximo
+ * IdleTimeout y, una vez iniciada, debe terminar de llegar dentro de ReadTimeout
+ * return: @[]byte: solicitud del cliente en bytes
+ *         @error: errRequestTooLarge si la solicitud supera MaxRequestSize, err si fallo la lectura */
+func (client *Client) readRequest() ([]byte, error) {
+
+	client.setReadDeadline(client.config.IdleTimeout)
+
+	if _, err := client.reader.Peek(1); err != nil { // Esperamos el inicio de la siguiente solicitud
+		return nil, err
+	}
+
+	client.setReadDeadline(client.config.ReadTimeout)
+
+	if client.config.MaxRequestSize <= 0 { // Sin limite de tamaño
+		return client.reader.ReadBytes('\n')
+	}
+
+	line, err := client.reader.ReadSlice('\n')
+
+	if err == bufio.ErrBufferFull { // La solicitud no cabe en el lector, descartamos el resto de la linea
+
+		for err == bufio.ErrBufferFull {
+			_, err = client.reader.ReadSlice('\n')
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		return nil, errRequestTooLarge
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), line...), nil // Copiamos la solicitud, el lector reutiliza su memoria en la siguiente lectura
+}
+
+/* Funcion
+ * Nombre: setReadDeadline
+ * Descripcion: Establece el tiempo maximo para la siguiente lectura de la conexion
+ * @timeout: tiempo de espera, cero para no tener limite */
+func (client *Client) setReadDeadline(timeout time.Duration) {
+
+	deadline := time.Time{}
+
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := client.connection.SetReadDeadline(deadline); err != nil {
+		client.log().Warn("set read deadline failed", "error", err)
+	}
+}
+
 /* Funciones
  * Nombre: requestHandler
  * Descripcion: Funcion encargada de manejar una solicitud del cliente
  * @request solicitud del cliente en bytes */
 func (client *Client) requestHandler(request []byte) {
 
-	// analizando la solicitud del cliente con la libreria bytes
-	cmd := bytes.ToUpper(bytes.TrimSpace(bytes.Split(request, []byte(" "))[0])) //el comando (cmd) sera el primer corte de la solicitud segun los espacios en blanco
-	args := bytes.TrimSpace(bytes.TrimPrefix(request, cmd))                     // Los argumentos (args) corresponderan al corte de la solicitud (request) menos el comando (cmd)
+	tag, untagged, tagErr := splitTag(request) // Separamos la etiqueta opcional de la solicitud
+	client.tag, client.dispatched = tag, false
 
-	if string(cmd) == "" { // Manejamos que el comando no sea vacio
-		//client.WriteResponse("FALSE")
+	defer func() { // Las solicitudes que no llegan al servidor se confirman aqui, despues de su error si lo hubo
+		if tag != "" && !client.dispatched {
+			client.WriteResponse("DONE " + tag)
+		}
+	}()
 
-	} else {
+	defer func() { // Un error inesperado manejando la solicitud no debe terminar la conexion del cliente
+		if r := recover(); r != nil {
+			client.log().Error("panic handling request", "panic", r)
+			client.respondError(errors.New("internal error handling request"))
+		}
+	}()
 
-		switch string(cmd) { //Segun sea el comando
+	atomic.StoreInt64(&client.lastSeen, time.Now().UnixNano()) // Cualquier solicitud demuestra que el cliente sigue conectado
 
-		case "JOIN": // Solicitud para entrar a un canal
-			if err := client.joinChannel(args); err != nil {
-				client.writeError(err)
-			}
+	cmd, args, err := parseRequest(untagged) // Analizamos la solicitud segun el esquema de su comando
 
-		case "LEAVE": // Solicitud para salir de un canal
-			if err := client.leaveChannel(args); err != nil {
-				client.writeError(err)
-			}
+	if tagErr != nil {
+		err = tagErr
+	}
 
-		case "CREATE": //Solicitud para crear un canal
-			if err := client.createChannel(args); err != nil {
-				client.writeError(err)
-			}
+	atomic.AddInt64(&client.metrics.bytesIn, int64(len(request)))
+	client.metrics.command(cmd)
 
-		case "LIST_CHN": // Solicitud para listar los canales existentes
-			if err := client.listChannels(); err != nil {
-				client.writeError(err)
-			}
+	if !client.allow(request, cmd) { // Manejamos que el cliente no exceda los limites de solicitudes
+		return
+	}
 
-		case "MSG": //Solicitud para envio de un archivo a un canal existente
-			if err := client.sendMsg(args); err != nil {
-				client.writeError(err)
-			}
+	if err != nil { // Informamos al cliente el error de sintaxis
+		client.respondError(err)
+		return
+	}
 
-		case "LIST_MSG": // Solicitud para listar los mensajes de un canal
-			if err := client.listMsg(args); err != nil {
-				client.writeError(err)
-			}
+	switch cmd { //Segun sea el comando
 
-		case "LIST_USR": // Solicitud para listar los clientes conectados en un canal
-			if err := client.listUsrChannel(args); err != nil {
-				client.writeError(err)
-			}
+	case "": // Las solicitudes vacias se ignoran
+
+	case "REG": // Solicitud para registrar el nombre del cliente
+		if err := client.register(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "JOIN": // Solicitud para entrar a un canal
+		if err := client.joinChannel(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "LEAVE": // Solicitud para salir de un canal
+		if err := client.leaveChannel(args); err != nil {
+			client.respondError(err)
+		}
 
-		default: //Si el comando no es reconocido en las posibilidades
-			//client.WriteResponse("FALSE") //Informamos que es invalido
+	case "CREATE": //Solicitud para crear un canal
+		if err := client.createChannel(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "LIST_CHN": // Solicitud para listar los canales existentes
+		if err := client.listChannels(); err != nil {
+			client.respondError(err)
+		}
+
+	case "MSG": //Solicitud para envio de un archivo a un canal existente
+		if err := client.sendMsg(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "LIST_MSG": // Solicitud para listar los mensajes de un canal
+		if err := client.listMsg(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "LIST_USR": // Solicitud para listar los clientes conectados en un canal
+		if err := client.listUsrChannel(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "INVITE": // Solicitud para invitar a un cliente a un canal
+		if err := client.inviteClient(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "MODE": // Solicitud para cambiar los modos de un canal
+		if err := client.setMode(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "TOPIC": // Solicitud para consultar o cambiar el tema de un canal
+		if err := client.topicChannel(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "EDIT": // Solicitud para editar un mensaje de un canal
+		if err := client.editMessage(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "DELETE": // Solicitud para eliminar un mensaje de un canal
+		if err := client.deleteMessage(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "THREAD": // Solicitud para listar las respuestas de un mensaje
+		if err := client.listThread(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "REACT": // Solicitud para reaccionar a un mensaje de un canal
+		if err := client.react(args, REACT); err != nil {
+			client.respondError(err)
+		}
+
+	case "UNREACT": // Solicitud para quitar una reaccion a un mensaje de un canal
+		if err := client.react(args, UNREACT); err != nil {
+			client.respondError(err)
+		}
+
+	case "DM": // Solicitud para enviar un mensaje directo a un cliente
+		if err := client.sendDirect(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "LIST_DM": // Solicitud para listar las conversaciones directas del cliente
+		if err := client.listDirects(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "PING": // Solicitud para comprobar que el servidor siga conectado
+		if err := client.ping(args); err != nil {
+			client.respondError(err)
+		}
+
+	case "PONG": // Respuesta a un PING del servidor
+		if err := client.pong(args); err != nil {
+			client.respondError(err)
 		}
 	}
 }
 
 /** FUNCIONES PARA ASIGNAR AL INTERMEDIARIO DEL CLIENTE CON EL SERVIDOR UN NUEVO COMANDO **/
 /** 																					 **/
-/** @args: argumentos escritos por el cliente											 **/
+/** @args: argumentos del comando, validados contra su esquema por parseRequest			 **/
 /** return: @error: nil si fue correcta la creacion del comando, err si fallo.           **/
 
-//Comando para que un cliente se conecte a un canal
-func (client *Client) joinChannel(args []byte) error {
+// Comando para que un cliente registre su nombre
+func (client *Client) register(args [][]byte) error {
 
-	channel, err := client.getArg(args, 0) // Manejamos que el primer argumento no sea vacio
+	nickname := args[0] // Primer argumento, correspondiente al nombre a registrar
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
+	if err := validateNickname(string(nickname)); err != nil { // Manejamos que el nombre cumpla las reglas de nombrado
 		return err
 	}
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
-		id:      JOIN,
-	}
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		sender:   client,
+		target:   string(nickname),
+		password: string(args[1]), // Contraseña opcional del nombre
+		id:       REG,
+	})
 	return nil
 }
 
-// Comando para que un cliente se desconecte de un canal
-func (client *Client) leaveChannel(args []byte) error {
+// Comando para que un cliente se conecte a un canal
+func (client *Client) joinChannel(args [][]byte) error {
 
-	channel, err := client.getArg(args, 0) // Obtenemos el primer argumento, correspondiente al nombre del canal a salir
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel:  string(args[0]), // Nombre del canal a entrar
+		sender:   client,
+		password: string(args[1]), // Contraseña opcional del canal
+		id:       JOIN,
+	})
+	return nil
+}
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
-		return err
-	}
+// Comando para que un cliente se desconecte de un canal
+func (client *Client) leaveChannel(args [][]byte) error {
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal a salir
+		sender:  client,
 		id:      LEAVE,
-	}
+	})
 	return nil
 }
 
 // Comando para crear un canal
-func (client *Client) createChannel(args []byte) error {
+func (client *Client) createChannel(args [][]byte) error {
 
-	channel, err := client.getArg(args, 0) // Obtenemos el primer argumento, correspondiente al nombre del canal a crear
+	name := normalizeChannelName(string(args[0])) // Normalizamos el nombre del canal a crear
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
+	if err := validateChannelName(name); err != nil { // Manejamos que el nombre cumpla las reglas de nombrado
 		return err
 	}
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: name,
+		sender:  client,
 		id:      CREATE,
-	}
+	})
 	return nil
 }
 
 // Comando para listar los canales
 func (client *Client) listChannels() error {
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		sender: *client,
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		sender: client,
 		id:     LIST_CHN,
-	}
+	})
 	return nil
 }
 
-// Comando para enviar un mensaje
-func (client *Client) sendMsg(args []byte) error {
+// Comando para enviar un mensaje, o una respuesta si se indica el mensaje al que responde
+func (client *Client) sendMsg(args [][]byte) error {
 
-	channel, err := client.getArg(args, 0) // Obtenemos el primer argumento, correspondiente al nombre del canal destinatario
+	var parent uint64
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
-		return err
+	if args[3] != nil {
+		id, err := parseMessageID(args[3])
+		if err != nil {
+			return err
+		}
+		parent = id
 	}
 
-	message, err := client.getArg(args, 1) // Obtenemos el segundo argumento, correspondiente al mensaje
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal destinatario
+		sender:  client,
+		content: args[1], // Mensaje
+		file:    args[2], // Archivo opcional en base64
+		message: parent,  // Mensaje al que responde, cero si no es una respuesta
+		id:      MSG,
+	})
+
+	return nil
+}
+
+// Comando para listar los mensajes de un canal
+func (client *Client) listMsg(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal a listar los mensajes
+		sender:  client,
+		id:      LIST_MSG,
+	})
+
+	return nil
+}
 
-	if err != nil { // Manejamos que el segundo argumento no sea vacio
+// Comando para listar los usuarios de un canal.
+func (client *Client) listUsrChannel(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal a listar los usuarios
+		sender:  client,
+		id:      LIST_USR,
+	})
+
+	return nil
+}
+
+// Comando para invitar a un cliente a un canal
+func (client *Client) inviteClient(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal
+		sender:  client,
+		target:  string(args[1]), // Cliente invitado
+		id:      INVITE,
+	})
+
+	return nil
+}
+
+// Comando para cambiar los modos de un canal
+func (client *Client) setMode(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel:  string(args[0]), // Nombre del canal
+		sender:   client,
+		mode:     string(args[1]), // Modo: +i, -i, +p, -p, +k, -k
+		password: string(args[2]), // Contraseña opcional para +k
+		id:       MODE,
+	})
+
+	return nil
+}
+
+// Comando para consultar el tema de un canal, o cambiarlo si se envia un tema
+func (client *Client) topicChannel(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal
+		sender:  client,
+		content: args[1], // Tema opcional, si no se envia se consulta el tema actual
+		desc:    args[2], // Descripcion opcional
+		id:      TOPIC,
+	})
+
+	return nil
+}
+
+// Comando para editar un mensaje de un canal
+func (client *Client) editMessage(args [][]byte) error {
+
+	id, err := parseMessageID(args[1])
+
+	if err != nil {
 		return err
 	}
 
-	file, err := client.getArg(args, 2) // Obtenemos el tercer argumento, correspondiente a un archivo en base64
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal del mensaje
+		sender:  client,
+		message: id,      // Identificador del mensaje
+		content: args[2], // Contenido nuevo
+		id:      EDIT,
+	})
+
+	return nil
+}
+
+// Comando para eliminar un mensaje de un canal
+func (client *Client) deleteMessage(args [][]byte) error {
+
+	id, err := parseMessageID(args[1])
 
-	if err != nil { // Manejamos que el tercer argumento no sea vacio
+	if err != nil {
 		return err
 	}
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
-		content: message,
-		file:    file,
-		id:      MSG,
-	}
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal del mensaje
+		sender:  client,
+		message: id, // Identificador del mensaje
+		id:      DELETE,
+	})
 
 	return nil
 }
 
-// Comando para listar los mensajes de un canal
-func (client *Client) listMsg(args []byte) error {
+// Comando para listar el hilo de respuestas de un mensaje
+func (client *Client) listThread(args [][]byte) error {
 
-	channel, err := client.getArg(args, 0) // Obtenemos el primer argumento, correspondiente al nombre del canal a listar los mensajes
+	id, err := parseMessageID(args[1])
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
+	if err != nil {
 		return err
 	}
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
-		id:      LIST_MSG,
-	}
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel: string(args[0]), // Nombre del canal del mensaje
+		sender:  client,
+		message: id, // Identificador del mensaje
+		id:      THREAD,
+	})
 
 	return nil
 }
 
-// Comando para listar los usuarios de un canal.
-func (client *Client) listUsrChannel(args []byte) error {
+// Comando para agregar o quitar una reaccion a un mensaje de un canal
+func (client *Client) react(args [][]byte, id ID) error {
 
-	channel, err := client.getArg(args, 0) // Obtenemos el primer argumento, correspondiente al nombre del canal a listar los usuarios
+	message, err := parseMessageID(args[1])
 
-	if err != nil { // Manejamos que el primer argumento no sea vacio
+	if err != nil {
 		return err
 	}
 
-	client.middlemane <- Command{ // Asignamos al intermediario el nuevo comando
-		channel: string(channel),
-		sender:  *client,
-		id:      LIST_USR,
+	reaction, err := parseReaction(args[2])
+
+	if err != nil {
+		return err
 	}
 
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		channel:  string(args[0]), // Nombre del canal del mensaje
+		sender:   client,
+		message:  message,  // Identificador del mensaje
+		reaction: reaction, // Emoji o texto corto
+		id:       id,       // REACT o UNREACT
+	})
+
+	return nil
+}
+
+// Comando para enviar un mensaje directo a un cliente
+func (client *Client) sendDirect(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		sender:  client,
+		target:  string(args[0]), // Nombre del destinatario
+		content: args[1],         // Mensaje
+		file:    args[2],         // Archivo opcional en base64
+		id:      DM,
+	})
+
+	return nil
+}
+
+// Comando para listar las conversaciones directas, o los mensajes de una conversacion si se indica el otro participante
+func (client *Client) listDirects(args [][]byte) error {
+
+	client.send(Command{ // Asignamos al intermediario el nuevo comando
+		sender: client,
+		target: string(args[0]), // Otro participante opcional
+		id:     LIST_DM,
+	})
+
+	return nil
+}
+
+// Comando para comprobar que el servidor siga conectado, se responde sin pasar por el servidor
+func (client *Client) ping(args [][]byte) error {
+
+	client.respond(strings.TrimSpace("PONG " + string(args[0]))) // Devolvemos el token opcional del PING
+	return nil
+}
+
+// Comando para responder a un PING del servidor, la solicitud ya registro que el cliente sigue conectado
+func (client *Client) pong(args [][]byte) error {
+
 	return nil
 }
 
 /** FIN FUNCIONES PARA COMANDOS **/
 
 /* Funcion
- * Nombre: getArg
- * Descripcion: Obtiene un argumento segun la posicion indicada en los argumentos totales
- * @args: argumentos escritos por el cliente.
- * @position: posicion deseada del argumento a tomar
- * return: @[]byte: argumento deseado en bytes
-  		   @error:  nil si existe el argumento deseado, err si esta vacio. */
-func (client *Client) getArg(args []byte, position int) ([]byte, error) {
+ * Nombre: send
+ * Descripcion: Entrega un comando al servidor y espera a que se procese. Los comandos sobre un canal los recibe
+ * la particion del canal, los demas el intermediario del servidor. Como cada rutina procesa sus comandos por
+ * separado, esperar el resultado mantiene el orden de las solicitudes del cliente, por ejemplo un REG seguido
+ * de un JOIN entra al canal con el nombre ya registrado. Si el servidor se apaga deja de esperar e informa al
+ * cliente que el comando no se proceso
+ * @cmd: comando a entregar */
+func (client *Client) send(cmd Command) {
+
+	cmd.received = time.Now()
+	cmd.tag = client.tag // Quien procese el comando confirma la etiqueta
+	cmd.reply = make(chan struct{})
+	client.dispatched = true
+
+	if logger := client.log(); logger.Enabled(LevelDebug) { // Evitamos armar los campos si no se registran
+
+		fields := []interface{}{"command", cmd.id}
+
+		if cmd.channel != "" {
+			fields = append(fields, "channel", cmd.channel)
+		}
+		if cmd.target != "" {
+			fields = append(fields, "target", cmd.target)
+		}
+		logger.Debug("command", fields...)
+	}
 
-	arg01 := bytes.Split(args, []byte(";;"))[position] //Tomamos un argumento segun la posicion solicitada de los argumentos
+	target := client.middlemane
 
-	if len(arg01) == 0 { //Manejamos que no este vacio el argumento solicitado
+	if cmd.channel != "" {
+		select {
+		case <-client.online: // Las particiones buscan al cliente en el servidor, esperamos a que este registrado
+		case <-client.stopped:
+			client.stoppedError()
+			return
+		}
+		target = shardFor(client.shards, cmd.channel).commands
+	}
 
-		return arg01, errors.New("empty arg01")
+	select {
+	case target <- cmd:
+	case <-client.stopped:
+		client.stoppedError()
+		return
 	}
-	return arg01, nil
+
+	// Run y las particiones terminan cada comando que reciben aunque el servidor se apague, y su respuesta usa
+	// la etiqueta de esta solicitud, asi que esperamos a que termine antes de leer la siguiente
+	<-cmd.reply
+}
+
+/* Funcion
+ * Nombre: stoppedError
+ * Descripcion: Informa al cliente que su comando no se proceso porque el servidor se apago, la solicitud se
+ * confirma con su DONE como las que no llegan al servidor */
+func (client *Client) stoppedError() {
+
+	client.dispatched = false
+	client.respondError(errServerStopped)
+}
+
+/* Funcion
+ * Nombre: allow
+ * Descripcion: Consume de los limites del cliente y de su IP una solicitud. Si los excede informa al cliente,
+ * y si la IP acumula demasiados excesos la banea temporalmente y cierra la conexion
+ * @request: solicitud del cliente en bytes
+ * @cmd: comando de la solicitud
+ * return: @bool: true si la solicitud puede procesarse */
+func (client *Client) allow(request []byte, cmd string) bool {
+
+	now := time.Now()
+	ip := HostOf(client.address)
+	message := cmd == "MSG" || cmd == "DM" || cmd == "EDIT" || cmd == "REACT"
+
+	if client.limits.allow(len(request), message, now) && client.limiter.allowIP(ip, len(request), message, now) {
+		return true
+	}
+
+	if client.limiter.strike(ip, now) { // Demasiados excesos, baneamos la IP y cerramos la conexion
+		client.log().Warn("ip banned", "ip", ip, "duration", client.limiter.config.BanDuration)
+		client.disconnect(errors.New("banned for " + client.limiter.config.BanDuration.String() + " due to flooding"))
+		return false
+	}
+
+	client.log().Debug("rate limit exceeded", "command", cmd)
+	client.respondError(errRateLimited)
+	return false
+}
+
+/* Funcion
+ * Nombre: name
+ * Descripcion: Retorna el nombre con el que se identifica al cliente */
+func (client *Client) name() string {
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.nickname != "" { // Si el cliente se registro usamos su nombre
+		return client.nickname
+	}
+	return client.address.String()
+}
+
+/* Funcion
+ * Nombre: log
+ * Descripcion: Retorna el registro de la conexion, con el nombre del cliente si se registro */
+func (client *Client) log() *Logger {
+
+	client.mu.Lock()
+	nickname := client.nickname
+	client.mu.Unlock()
+
+	if nickname != "" {
+		return client.logger.With("client", nickname)
+	}
+	return client.logger
+}
+
+/* Funcion
+ * Nombre: setNickname
+ * Descripcion: Cambia el nombre registrado del cliente
+ * @nickname: nombre registrado
+ * @verified: true si el cliente demostro ser el dueño del nombre con su contraseña */
+func (client *Client) setNickname(nickname string, verified bool) {
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.nickname, client.verified = nickname, verified
+}
+
+/* Funcion
+ * Nombre: account
+ * Descripcion: Retorna el nombre registrado del cliente si lo registro con su contraseña
+ * return: @string: nombre del cliente
+ *         @bool: true si el nombre esta verificado */
+func (client *Client) account() (string, bool) {
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.nickname, client.verified
+}
+
+/* Funcion
+ * Nombre: seen
+ * Descripcion: Retorna la ultima vez que se recibio una solicitud del cliente */
+func (client *Client) seen() time.Time {
+
+	return time.Unix(0, atomic.LoadInt64(&client.lastSeen))
 }
 
 /* Funcion
  * Nombre: WriteResponse
- * Descripcion: Escribe a la conexion del cliente la respuesta del servidor
+ * Descripcion: Agrega a la cola de salida del cliente la respuesta del servidor
  * @res: respuesta dada */
 func (client *Client) WriteResponse(res string) {
 
-	if _, err := client.connection.Write([]byte(res + "\n")); err != nil {
-		client.writeError(err)
-		return
+	client.enqueue([]byte(res + "\n"))
+}
+
+/* Funcion
+ * Nombre: respond
+ * Descripcion: Agrega a la cola de salida la respuesta a la solicitud en curso del cliente, con el prefijo
+ * @[etiqueta] si la solicitud tenia etiqueta, asi el cliente la distingue de los eventos. Solo debe llamarse
+ * mientras se procesa la solicitud: el cliente espera su comando y no cambia la etiqueta hasta que termine
+ * @res: respuesta dada */
+func (client *Client) respond(res string) {
+
+	if client.tag != "" {
+		res = "@" + client.tag + " " + res
 	}
+	client.WriteResponse(res)
+}
+
+/* Funcion
+ * Nombre: respondError
+ * Descripcion: Responde un error a la solicitud en curso del cliente, con su etiqueta igual que respond
+ * @e: error dado */
+func (client *Client) respondError(e error) {
+
+	atomic.AddInt64(&client.metrics.errors, 1)
+	client.respond("ERROR " + e.Error())
+	client.log().Debug("error response", "error", e)
 }
 
 /* Funcion
  * Nombre: writeError
- * Descripcion: Escribe a la conexion del cliente un error surgido en el servidor
+ * Descripcion: Agrega a la cola de salida del cliente un error surgido fuera de una solicitud, sin etiqueta,
+ * como una desconexion o una solicitud que no se pudo leer
  * @error: error dado */
 func (client *Client) writeError(e error) {
 
-	if _, err := client.connection.Write([]byte("ERROR " + e.Error() + "\n")); err != nil {
-		log.Println(err)
-		return
+	atomic.AddInt64(&client.metrics.errors, 1)
+	client.enqueue([]byte("ERROR " + e.Error() + "\n"))
+	client.log().Debug("error response", "error", e)
+}
+
+/* Funcion
+ * Nombre: setWriteDeadline
+ * Descripcion: Establece el tiempo maximo para la siguiente escritura a la conexion segun WriteTimeout */
+func (client *Client) setWriteDeadline() {
+
+	deadline := time.Time{}
+
+	if client.config.WriteTimeout > 0 {
+		deadline = time.Now().Add(client.config.WriteTimeout)
+	}
+
+	if err := client.connection.SetWriteDeadline(deadline); err != nil {
+		client.log().Warn("set write deadline failed", "error", err)
 	}
-	log.Println(e)
 }
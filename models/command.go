@@ -1,24 +1,76 @@
 package models
 
+import "time"
+
 type ID int
 
 // Comandos disponibles en el protocolo personalizado
 const (
-	REG      ID = iota
-	JOIN        // Cliente ingresa a un canal
-	LEAVE       // Cliente sale de un canal
-	MSG         // Envia un mensaje
-	CREATE      // Crea un canal
-	LIST_CHN    // Lista los canales
-	LIST_MSG    // Lista los mensajes de un canal
-	LIST_USR    // Lista los usuarios de un canal
+	REG      ID = iota // Cliente registra su nombre
+	JOIN               // Cliente ingresa a un canal
+	LEAVE              // Cliente sale de un canal
+	MSG                // Envia un mensaje
+	CREATE             // Crea un canal
+	LIST_CHN           // Lista los canales
+	LIST_MSG           // Lista los mensajes de un canal
+	LIST_USR           // Lista los usuarios de un canal
+	INVITE             // Invita a un cliente a un canal
+	MODE               // Cambia los modos de un canal
+	DM                 // Envia un mensaje directo a un cliente
+	LIST_DM            // Lista las conversaciones directas o los mensajes de una de ellas
+	TOPIC              // Consulta o cambia el tema de un canal
+	EDIT               // Edita un mensaje de un canal
+	DELETE             // Elimina un mensaje de un canal
+	THREAD             // Lista las respuestas de un mensaje
+	REACT              // Reacciona a un mensaje de un canal
+	UNREACT            // Quita una reaccion a un mensaje de un canal
+
+	numCommands // Cantidad de comandos, no es un comando. Los comandos nuevos van antes
 )
 
+// Nombres de los comandos en el protocolo, en el orden de sus identificadores
+var commandNames = [numCommands]string{"REG", "JOIN", "LEAVE", "MSG", "CREATE", "LIST_CHN", "LIST_MSG", "LIST_USR", "INVITE", "MODE", "DM", "LIST_DM", "TOPIC", "EDIT", "DELETE", "THREAD", "REACT", "UNREACT"}
+
+/* Funcion
+ * Nombre: String
+ * Descripcion: Retorna el nombre del comando en el protocolo */
+func (id ID) String() string {
+
+	if id < 0 || int(id) >= len(commandNames) {
+		return "UNKNOWN"
+	}
+	return commandNames[id]
+}
+
 // Estructura para la creacion de un comando
 type Command struct {
-	id      ID     // Identificador del comando
-	channel string // Nombre del canal a crear si es el caso
-	sender  Client // Emisor del comando
-	content []byte // Contenido de un mensaje
-	file    []byte // Base64 de un archivo
+	id       ID            // Identificador del comando
+	channel  string        // Nombre del canal a crear si es el caso
+	sender   *Client       // Emisor del comando
+	content  []byte        // Contenido de un mensaje o tema del canal (TOPIC)
+	file     []byte        // Base64 de un archivo
+	target   string        // Nombre del cliente al que se refiere el comando (REG, INVITE, DM, LIST_DM)
+	message  uint64        // Identificador del mensaje al que se refiere el comando (EDIT, DELETE, THREAD, REACT, UNREACT) o al que responde (MSG)
+	reaction string        // Emoji o texto corto de la reaccion (REACT, UNREACT)
+	mode     string        // Modo a cambiar en el canal (MODE)
+	password string        // Contraseña del nombre (REG) o del canal (JOIN, MODE)
+	desc     []byte        // Descripcion del canal (TOPIC), nil si no se cambia
+	received time.Time     // Momento en que el cliente envio el comando al servidor
+	reply    chan struct{} // Se cierra al terminar de procesar el comando, nil si nadie espera el resultado
+	tag      string        // Etiqueta de la solicitud, se confirma con DONE [etiqueta] al procesar el comando
+}
+
+/* Funcion
+ * Nombre: done
+ * Descripcion: Confirma al cliente que su solicitud con etiqueta fue procesada, despues de todas sus respuestas,
+ * y libera a quien espera el resultado del comando */
+func (cmd Command) done() {
+
+	if cmd.tag != "" {
+		cmd.sender.WriteResponse("DONE " + cmd.tag)
+	}
+
+	if cmd.reply != nil {
+		close(cmd.reply)
+	}
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Estructura para la configuracion de los limites y el registro del servidor.
+// Un limite en cero se considera deshabilitado
+type Config struct {
+	CommandRate    float64       `default:"20"`          // Comandos por segundo permitidos a cada cliente
+	CommandBurst   int           `default:"40"`          // Rafaga maxima de comandos de cada cliente
+	MessageRate    float64       `default:"5"`           // Mensajes (MSG, DM, EDIT, REACT) por segundo permitidos a cada cliente
+	MessageBurst   int           `default:"10"`          // Rafaga maxima de mensajes de cada cliente
+	ByteRate       float64       `default:"65536"`       // Bytes por segundo permitidos a cada cliente
+	ByteBurst      int           `default:"262144"`      // Rafaga maxima de bytes de cada cliente
+	IPCommandRate  float64       `default:"50"`          // Comandos por segundo permitidos a todas las conexiones de una IP
+	IPCommandBurst int           `default:"100"`         // Rafaga maxima de comandos de una IP
+	IPMessageRate  float64       `default:"15"`          // Mensajes por segundo permitidos a todas las conexiones de una IP
+	IPMessageBurst int           `default:"30"`          // Rafaga maxima de mensajes de una IP
+	IPByteRate     float64       `default:"262144"`      // Bytes por segundo permitidos a todas las conexiones de una IP
+	IPByteBurst    int           `default:"1048576"`     // Rafaga maxima de bytes de una IP
+	BanStrikes     int           `default:"10"`          // Excesos del limite antes de banear temporalmente la IP
+	BanWindow      time.Duration `default:"1m"`          // Ventana de tiempo en la que se cuentan los excesos
+	BanDuration    time.Duration `default:"5m"`          // Duracion del baneo temporal
+	MaxConnsPerIP  int           `default:"10"`          // Conexiones simultaneas permitidas por IP
+	MaxRequestSize int           `default:"65536"`       // Tamaño maximo de una solicitud en bytes
+	ReadTimeout    time.Duration `default:"10s"`         // Tiempo maximo para terminar de recibir una solicitud ya iniciada
+	WriteTimeout   time.Duration `default:"10s"`         // Tiempo maximo para escribir una respuesta al cliente
+	IdleTimeout    time.Duration `default:"5m"`          // Tiempo maximo sin recibir solicitudes del cliente
+	PingInterval   time.Duration `default:"30s"`         // Intervalo entre los PING que envia el servidor a cada cliente
+	PingMisses     int           `default:"3"`           // PING sin respuesta antes de desconectar al cliente
+	QueueSize      int           `default:"256"`         // Respuestas que caben en la cola de salida de cada cliente, cero usa DefaultQueueSize
+	QueuePolicy    string        `default:"drop-oldest"` // Politica con la cola llena: drop-oldest o disconnect
+	LogLevel       string        `default:"info"`        // Nivel minimo del registro: debug, info, warn o error
+	LogFormat      string        `default:"logfmt"`      // Formato del registro: logfmt o json
+}
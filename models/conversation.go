@@ -0,0 +1,62 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Maximo de mensajes directos guardados para un destinatario desconectado
+const maxPendingDirects = 100
+
+// Estructura para la creacion de una conversacion privada entre dos clientes
+type Conversation struct {
+	participants [2]string  // Nombres de los participantes de la conversacion
+	date         time.Time  // Fecha del ultimo mensaje
+	messages     []*Message // Mensajes de la conversacion en orden de llegada
+}
+
+/* Funcion
+ * Nombre: NewConversation
+ * Descripcion: Funcion encargada de crear una conversacion apartir de la estructura */
+func NewConversation(first string, second string) *Conversation {
+
+	participants := []string{first, second}
+	sort.Strings(participants) // Ordenamos los participantes para que la conversacion sea la misma sin importar quien la inicie
+
+	return &Conversation{
+		participants: [2]string{participants[0], participants[1]},
+		date:         time.Now(),
+		messages:     make([]*Message, 0),
+	}
+}
+
+/* Funcion
+ * Nombre: conversationKey
+ * Descripcion: Retorna la llave con la que se guarda la conversacion entre dos clientes */
+func conversationKey(first string, second string) string {
+
+	if first > second {
+		first, second = second, first
+	}
+	return first + " " + second
+}
+
+/* Funcion
+ * Nombre: peer
+ * Descripcion: Retorna el otro participante de la conversacion
+ * @name: nombre del participante que consulta */
+func (conversation *Conversation) peer(name string) string {
+
+	if conversation.participants[0] == name {
+		return conversation.participants[1]
+	}
+	return conversation.participants[0]
+}
+
+/* Funcion
+ * Nombre: hasParticipant
+ * Descripcion: Indica si el cliente dado participa en la conversacion */
+func (conversation *Conversation) hasParticipant(name string) bool {
+
+	return conversation.participants[0] == name || conversation.participants[1] == name
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tipos de eventos del servidor
+const (
+	EventClientConnected    = "client_connected"
+	EventClientDisconnected = "client_disconnected"
+	EventClientRegistered   = "client_registered"
+	EventChannelCreated     = "channel_created"
+	EventChannelUpdated     = "channel_updated" // Cambio de miembros, tema o modos
+	EventChannelDeleted     = "channel_deleted"
+	EventMemberJoined       = "member_joined" // Un cliente entro a un canal, se publica ademas de channel_updated
+	EventMemberLeft         = "member_left"   // Un cliente salio de un canal o se desconecto
+	EventMessage            = "message"
+	EventMessageEdited      = "message_edited"
+	EventMessageDeleted     = "message_deleted"
+	EventMessageReacted     = "message_reacted" // Se agrego o se quito una reaccion de un mensaje
+	EventStats              = "stats"
+)
+
+// Estructura para un evento del servidor, solo tiene los datos que corresponden a su tipo
+type Event struct {
+	Type    string       `json:"type"`
+	Time    time.Time    `json:"time"`
+	Client  *ClientInfo  `json:"client,omitempty"`
+	Channel *ChannelInfo `json:"channel,omitempty"`
+	Message *MessageInfo `json:"message,omitempty"`
+	Status  *Status      `json:"status,omitempty"`
+}
+
+// Estructura para los suscriptores a los eventos del servidor
+type events struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]bool
+	count       int32 // Cantidad de suscriptores, se consulta sin bloquear antes de armar un evento
+}
+
+/* Funcion
+ * Nombre: active
+ * Descripcion: Indica si hay suscriptores, asi los eventos solo se arman cuando alguien los recibe */
+func (e *events) active() bool {
+
+	return atomic.LoadInt32(&e.count) > 0
+}
+
+/* Funcion
+ * Nombre: publish
+ * Descripcion: Entrega un evento a todos los suscriptores sin bloquear, un suscriptor lento pierde el evento
+ * @event: evento a entregar */
+func (e *events) publish(event Event) {
+
+	event.Time = time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for subscriber := range e.subscribers {
+		select {
+		case subscriber <- event:
+		default: // El suscriptor no alcanza a leer sus eventos
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: Subscribe
+ * Descripcion: Suscribe a los eventos del servidor
+ * @size: eventos que se guardan mientras el suscriptor no los lee, los siguientes se pierden
+ * return: @<-chan Event: canal de eventos
+ *         @func(): cancela la suscripcion */
+func (server *Server) Subscribe(size int) (<-chan Event, func()) {
+
+	e := server.events
+	subscriber := make(chan Event, size)
+
+	e.mu.Lock()
+	e.subscribers[subscriber] = true
+	atomic.AddInt32(&e.count, 1)
+	e.mu.Unlock()
+
+	var once sync.Once
+
+	return subscriber, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subscribers, subscriber)
+			atomic.AddInt32(&e.count, -1)
+			e.mu.Unlock()
+		})
+	}
+}
+
+/* Funcion
+ * Nombre: clientEvent
+ * Descripcion: Publica un evento sobre un cliente */
+func (server *Server) clientEvent(kind string, client *Client) {
+
+	if server.events.active() {
+		info := client.info()
+		server.events.publish(Event{Type: kind, Client: &info})
+	}
+}
+
+/* Funcion
+ * Nombre: channelEvent
+ * Descripcion: Publica un evento sobre un canal, debe llamarse con la particion del canal bloqueada */
+func (server *Server) channelEvent(kind string, channel *Channel) {
+
+	if server.events.active() {
+		info := channel.info()
+		server.events.publish(Event{Type: kind, Channel: &info})
+	}
+}
+
+/* Funcion
+ * Nombre: memberEvent
+ * Descripcion: Publica un evento sobre un miembro de un canal, debe llamarse con la particion del canal bloqueada */
+func (server *Server) memberEvent(kind string, channel *Channel, client *Client) {
+
+	if server.events.active() {
+		channelInfo := channel.info()
+		clientInfo := client.info()
+		server.events.publish(Event{Type: kind, Channel: &channelInfo, Client: &clientInfo})
+	}
+}
+
+/* Funcion
+ * Nombre: messageEvent
+ * Descripcion: Publica un evento sobre un mensaje de un canal, debe llamarse con la particion del canal bloqueada */
+func (server *Server) messageEvent(kind string, channel *Channel, message *Message) {
+
+	if server.events.active() {
+		channelInfo := channel.info()
+		messageInfo := message.info()
+		server.events.publish(Event{Type: kind, Channel: &channelInfo, Message: &messageInfo})
+	}
+}
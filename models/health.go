@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identificador interno de las sondas de salud, no es parte del protocolo
+const probe ID = -1
+
+/* Funcion
+ * Nombre: CheckEventLoop
+ * Descripcion: Comprueba que la rutina principal del servidor procese comandos, enviandole una sonda
+ * @timeout: tiempo maximo para que la sonda sea procesada
+ * return: @error: err si la rutina principal no respondio a tiempo */
+func (server *Server) CheckEventLoop(timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if !sendProbe(ctx, server.commands) {
+		return errors.New("event loop not responding")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: CheckStorage
+ * Descripcion: Comprueba que los canales y sus mensajes esten disponibles, enviando una sonda a cada particion.
+ * Las particiones procesan la sonda con sus canales bloqueados, como cualquier otro comando. Las sondas se envian
+ * a la vez, asi la comprobacion tarda como maximo timeout aunque varias particiones esten bloqueadas
+ * @timeout: tiempo maximo para que todas las particiones procesen la sonda
+ * return: @error: err con las particiones que no respondieron a tiempo */
+func (server *Server) CheckStorage(timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	results := make([]bool, len(server.shards))
+	var wg sync.WaitGroup
+
+	for i, shard := range server.shards {
+		wg.Add(1)
+		go func(i int, commands chan<- Command) {
+			defer wg.Done()
+			results[i] = sendProbe(ctx, commands)
+		}(i, shard.commands)
+	}
+	wg.Wait()
+
+	failed := make([]string, 0)
+
+	for i, ok := range results {
+		if !ok {
+			failed = append(failed, strconv.Itoa(i))
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New("channel shards " + strings.Join(failed, ",") + " not responding")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: sendProbe
+ * Descripcion: Envia una sonda a una rutina de comandos y espera a que la procese
+ * @ctx: contexto con el tiempo maximo para entregar y procesar la sonda
+ * @commands: canal de comandos de la rutina
+ * return: @bool: true si la sonda fue procesada a tiempo */
+func sendProbe(ctx context.Context, commands chan<- Command) bool {
+
+	reply := make(chan struct{})
+
+	select {
+	case commands <- Command{id: probe, reply: reply}:
+	case <-ctx.Done(): // La rutina no esta recibiendo comandos
+		return false
+	}
+
+	select {
+	case <-reply:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
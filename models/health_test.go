@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+/* Funcion
+ * Nombre: TestCheckStorage
+ * Descripcion: Las particiones bloqueadas se informan juntas y la comprobacion no tarda mas que su tiempo maximo */
+func TestCheckStorage(t *testing.T) {
+
+	server := NewServer()
+	server.startShards.Do(func() {
+		for _, shard := range server.shards {
+			go server.runShard(shard)
+		}
+	})
+
+	if err := server.CheckStorage(time.Second); err != nil {
+		t.Fatalf("CheckStorage with idle shards = %v", err)
+	}
+
+	for _, i := range []int{3, 7, 11} { // Particiones ocupadas con un comando que no termina
+		server.shards[i].mu.Lock()
+		defer server.shards[i].mu.Unlock()
+	}
+
+	start := time.Now()
+	err := server.CheckStorage(100 * time.Millisecond)
+
+	if err == nil || err.Error() != "channel shards 3,7,11 not responding" {
+		t.Fatalf("CheckStorage with blocked shards = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("CheckStorage took %v with a 100ms timeout", elapsed)
+	}
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Errores de las integraciones
+var (
+	ErrIntegrationNotFound = errors.New("integration not found")
+	ErrInvalidToken        = errors.New("invalid integration token")
+)
+
+// Estructura con los datos de una integracion: un sistema externo que publica en un canal con su token
+type Integration struct {
+	ID      uint64    `json:"id"`
+	Channel string    `json:"channel"`
+	Name    string    `json:"name"`            // Autor de los mensajes de la integracion
+	Token   string    `json:"token,omitempty"` // Solo se muestra al crear la integracion
+	Created time.Time `json:"created"`
+}
+
+// Estructura para las integraciones del servidor segun su token
+type integrations struct {
+	mu     sync.Mutex
+	tokens map[string]*Integration
+	ids    uint64
+}
+
+// Direccion de un emisor que no tiene conexion TCP, identifica a una integracion en sendMessage
+type integrationAddr struct {
+	name string
+}
+
+/* Funcion
+ * Nombre: Network
+ * Descripcion: Retorna la red de la direccion de una integracion */
+func (addr integrationAddr) Network() string {
+
+	return "integration"
+}
+
+/* Funcion
+ * Nombre: String
+ * Descripcion: Retorna la direccion de una integracion con su nombre */
+func (addr integrationAddr) String() string {
+
+	return "integration:" + addr.name
+}
+
+// Direccion de un emisor del propio servidor, identifica en sendMessage los mensajes de la API y de los bots locales
+type systemAddr struct {
+	name string
+}
+
+/* Funcion
+ * Nombre: Network
+ * Descripcion: Retorna la red de la direccion del servidor */
+func (addr systemAddr) Network() string {
+
+	return "system"
+}
+
+/* Funcion
+ * Nombre: String
+ * Descripcion: Retorna la direccion del servidor con el nombre del autor */
+func (addr systemAddr) String() string {
+
+	return "system:" + addr.name
+}
+
+/* Funcion
+ * Nombre: CreateIntegration
+ * Descripcion: Crea el token para que un sistema externo publique en un canal con un nombre
+ * @channelName: canal de la integracion
+ * @name: autor de los mensajes, con las mismas reglas que un nombre registrado con REG
+ * return: @Integration: integracion creada, con su token
+ *         @error: ErrChannelNotFound si el canal no existe, ErrInvalidArgument si el nombre no es valido */
+func (server *Server) CreateIntegration(channelName string, name string) (Integration, error) {
+
+	if err := validateMessage(name, []byte("-"), nil, 0); err != nil { // Validamos el nombre como el autor de un mensaje
+		return Integration{}, fmt.Errorf("%w: invalid name %q", ErrInvalidArgument, name)
+	}
+
+	info, err := server.Channel(channelName, true)
+	if err != nil {
+		return Integration{}, err
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return Integration{}, err
+	}
+
+	in := server.integrations
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.ids++
+	integration := &Integration{ID: in.ids, Channel: info.Name, Name: name, Token: hex.EncodeToString(token), Created: time.Now()}
+	in.tokens[integration.Token] = integration
+
+	server.Logger.Info("integration created", "component", "admin", "integration", integration.ID, "channel", integration.Channel, "name", name)
+	return *integration, nil
+}
+
+/* Funcion
+ * Nombre: Integrations
+ * Descripcion: Retorna las integraciones ordenadas por su identificador, sin sus tokens */
+func (server *Server) Integrations() []Integration {
+
+	in := server.integrations
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	list := make([]Integration, 0, len(in.tokens))
+
+	for _, integration := range in.tokens {
+		info := *integration
+		info.Token = ""
+		list = append(list, info)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+/* Funcion
+ * Nombre: DeleteIntegration
+ * Descripcion: Elimina una integracion, su token deja de ser valido
+ * return: @error: ErrIntegrationNotFound si no existe */
+func (server *Server) DeleteIntegration(id uint64) error {
+
+	in := server.integrations
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for token, integration := range in.tokens {
+		if integration.ID == id {
+			delete(in.tokens, token)
+			server.Logger.Info("integration deleted", "component", "admin", "integration", id)
+			return nil
+		}
+	}
+	return ErrIntegrationNotFound
+}
+
+/* Funcion
+ * Nombre: deleteIntegrations
+ * Descripcion: Elimina las integraciones de un canal eliminado */
+func (server *Server) deleteIntegrations(channelName string) {
+
+	in := server.integrations
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for token, integration := range in.tokens {
+		if channelKey(integration.Channel) == channelKey(channelName) {
+			delete(in.tokens, token)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: PostIntegration
+ * Descripcion: Publica el mensaje de una integracion en su canal, por el mismo camino que un MSG de un cliente
+ * @token: token de la integracion
+ * @content: contenido del mensaje
+ * @file: base64 opcional de un archivo
+ * return: @MessageInfo: mensaje publicado
+ *         @error: ErrInvalidToken si el token no existe, ErrInvalidMessage si el mensaje no es valido o
+ *                 ErrChannelNotFound si el canal ya no existe */
+func (server *Server) PostIntegration(token string, content []byte, file []byte) (MessageInfo, error) {
+
+	in := server.integrations
+	in.mu.Lock()
+	integration, ok := in.tokens[token]
+	in.mu.Unlock()
+
+	if !ok {
+		return MessageInfo{}, ErrInvalidToken
+	}
+
+	if err := validateMessage(integration.Name, content, file, server.Config.MaxRequestSize); err != nil {
+		return MessageInfo{}, err
+	}
+
+	shard := shardFor(server.shards, integration.Channel)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	message := server.sendMessage(integrationAddr{integration.Name}, integration.Channel, content, file, 0)
+
+	if message == nil { // El canal se elimino despues de crear la integracion
+		return MessageInfo{}, ErrChannelNotFound
+	}
+	return message.info(), nil
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Niveles del registro, de menor a mayor severidad
+type Level int
+
+const (
+	LevelDebug Level = iota // Detalle de cada solicitud
+	LevelInfo               // Eventos normales del servidor: conexiones, registros, canales
+	LevelWarn               // Situaciones anomalas que el servidor maneja: limites, rechazos, clientes lentos
+	LevelError              // Fallos del servidor
+)
+
+// Formatos de salida del registro
+const (
+	FormatLogfmt = "logfmt" // clave=valor en una linea
+	FormatJSON   = "json"   // Un objeto JSON por linea
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+/* Funcion
+ * Nombre: String
+ * Descripcion: Retorna el nombre del nivel */
+func (level Level) String() string {
+
+	if level < LevelDebug || level > LevelError {
+		return "unknown"
+	}
+	return levelNames[level]
+}
+
+/* Funcion
+ * Nombre: ParseLevel
+ * Descripcion: Convierte el nombre de un nivel en su valor
+ * @name: nombre del nivel: debug, info, warn o error
+ * return: @Level: nivel
+ *         @error: err si el nivel no existe */
+func ParseLevel(name string) (Level, error) {
+
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, errors.New("unknown log level " + name)
+}
+
+// Estructura para un registro estructurado con niveles. Los registros creados con With comparten la salida
+// y agregan sus campos a cada linea, asi cada conexion registra su identificador sin repetirlo en cada llamada
+type Logger struct {
+	output *logOutput    // Salida compartida por el registro y los registros derivados
+	fields []interface{} // Pares clave, valor que se agregan a cada linea
+}
+
+// Estructura para la salida de un registro
+type logOutput struct {
+	mu     sync.Mutex // Las lineas de distintas rutinas no se mezclan
+	writer io.Writer
+	level  Level // Nivel minimo que se escribe
+	json   bool  // Formato JSON, si no logfmt
+}
+
+/* Funcion
+ * Nombre: NewLogger
+ * Descripcion: Funcion encargada de crear un registro apartir de la estructura
+ * @writer: destino de las lineas del registro
+ * @level: nivel minimo a registrar
+ * @format: formato de las lineas, logfmt o json
+ * return: @*Logger: registro
+ *         @error: err si el nivel o el formato no existen */
+func NewLogger(writer io.Writer, level string, format string) (*Logger, error) {
+
+	minimum, err := ParseLevel(level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if format != FormatLogfmt && format != FormatJSON {
+		return nil, errors.New("unknown log format " + format)
+	}
+
+	return &Logger{output: &logOutput{writer: writer, level: minimum, json: format == FormatJSON}}, nil
+}
+
+/* Funcion
+ * Nombre: With
+ * Descripcion: Retorna un registro que agrega los campos dados a cada linea
+ * @keyValues: pares clave, valor */
+func (logger *Logger) With(keyValues ...interface{}) *Logger {
+
+	fields := make([]interface{}, 0, len(logger.fields)+len(keyValues))
+	fields = append(fields, logger.fields...)
+	fields = append(fields, keyValues...)
+
+	return &Logger{output: logger.output, fields: fields}
+}
+
+/* Funcion
+ * Nombre: Enabled
+ * Descripcion: Indica si el registro escribe las lineas de un nivel */
+func (logger *Logger) Enabled(level Level) bool {
+
+	return level >= logger.output.level
+}
+
+// Funciones para registrar una linea en cada nivel con un mensaje y pares clave, valor
+func (logger *Logger) Debug(msg string, keyValues ...interface{}) {
+	logger.log(LevelDebug, msg, keyValues)
+}
+
+func (logger *Logger) Info(msg string, keyValues ...interface{}) {
+	logger.log(LevelInfo, msg, keyValues)
+}
+
+func (logger *Logger) Warn(msg string, keyValues ...interface{}) {
+	logger.log(LevelWarn, msg, keyValues)
+}
+
+func (logger *Logger) Error(msg string, keyValues ...interface{}) {
+	logger.log(LevelError, msg, keyValues)
+}
+
+/* Funcion
+ * Nombre: log
+ * Descripcion: Escribe una linea en el formato de la salida si su nivel esta habilitado
+ * @level: nivel de la linea
+ * @msg: mensaje
+ * @keyValues: pares clave, valor de la linea, despues de los campos del registro */
+func (logger *Logger) log(level Level, msg string, keyValues []interface{}) {
+
+	if !logger.Enabled(level) {
+		return
+	}
+
+	fields := make([]interface{}, 0, 6+len(logger.fields)+len(keyValues))
+	fields = append(fields, "time", time.Now().Format(time.RFC3339Nano), "level", level.String(), "msg", msg)
+	fields = append(fields, logger.fields...)
+	fields = append(fields, keyValues...)
+
+	if len(fields)%2 != 0 { // Una clave sin valor
+		fields = append(fields, nil)
+	}
+
+	b := &strings.Builder{}
+
+	if logger.output.json {
+		writeJSON(b, fields)
+	} else {
+		writeLogfmt(b, fields)
+	}
+	b.WriteByte('\n')
+
+	logger.output.mu.Lock()
+	defer logger.output.mu.Unlock()
+
+	io.WriteString(logger.output.writer, b.String())
+}
+
+/* Funcion
+ * Nombre: writeLogfmt
+ * Descripcion: Escribe los campos como clave=valor separados por espacios, entre comillas si es necesario */
+func writeLogfmt(b *strings.Builder, fields []interface{}) {
+
+	for i := 0; i < len(fields); i += 2 {
+
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(fmt.Sprint(fields[i]))
+		b.WriteByte('=')
+
+		value := formatValue(fields[i+1])
+
+		if value == "" || strings.ContainsAny(value, " =\"\\\n\t") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+}
+
+/* Funcion
+ * Nombre: writeJSON
+ * Descripcion: Escribe los campos como un objeto JSON, en el orden dado */
+func writeJSON(b *strings.Builder, fields []interface{}) {
+
+	b.WriteByte('{')
+
+	for i := 0; i < len(fields); i += 2 {
+
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(fmt.Sprint(fields[i]))
+		b.Write(key)
+		b.WriteByte(':')
+
+		var value interface{} = fields[i+1]
+
+		switch v := value.(type) { // Los numeros y booleanos se conservan, lo demas se escribe como texto
+		case int, int64, uint64, float64, bool, nil:
+		default:
+			value = formatValue(v)
+		}
+
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			encoded, _ = json.Marshal(err.Error())
+		}
+		b.Write(encoded)
+	}
+
+	b.WriteByte('}')
+}
+
+/* Funcion
+ * Nombre: formatValue
+ * Descripcion: Convierte el valor de un campo en texto */
+func formatValue(value interface{}) string {
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
@@ -2,26 +2,109 @@ package models
 
 import (
 	"net"
+	"strconv"
 	"time"
 )
 
 // Estructura para la creacion de un mensaje
 type Message struct {
-	date    time.Time //Fecha del mensaje
-	sender  net.Addr  //Direccion del emisor
-	content []byte    //Contenido del mensaje
-	file    []byte    //Base64 de un archivo
+	id        uint64      //Identificador del mensaje, creciente dentro de su canal
+	date      time.Time   //Fecha del mensaje
+	sender    net.Addr    //Direccion del emisor
+	author    string      //Nombre del emisor
+	verified  bool        //El emisor registro su nombre con contraseña, asi su nombre lo identifica en otra conexion
+	content   []byte      //Contenido del mensaje
+	file      []byte      //Base64 de un archivo
+	edits     []Revision  //Contenidos anteriores del mensaje, del mas antiguo al mas reciente
+	edited    time.Time   //Fecha de la ultima edicion, cero si no se ha editado
+	deleted   time.Time   //Fecha de eliminacion, cero si no se ha eliminado
+	parent    uint64      //Identificador del mensaje raiz del hilo, cero si no es una respuesta
+	replies   []*Message  //Respuestas del hilo del mensaje, en orden de llegada
+	reactions []*reaction //Reacciones del mensaje, en el orden en que se usaron
+}
+
+// Estructura para un contenido anterior de un mensaje editado
+type Revision struct {
+	Content string    `json:"content"`
+	Date    time.Time `json:"date"` // Fecha en que se escribio este contenido
 }
 
 /* Funcion
  * Nombre: NewMessage
  * Descripcion: Funcion encargada de crear un nuevo mensaje apartir de la estructura */
-func NewMessage(sender net.Addr, content []byte, file []byte) *Message {
+func NewMessage(sender net.Addr, author string, content []byte, file []byte) *Message {
 
 	return &Message{
 		date:    time.Now(),
 		sender:  sender,
+		author:  author,
 		content: content,
 		file:    file,
 	}
 }
+
+/* Funcion
+ * Nombre: format
+ * Descripcion: Retorna el mensaje en el formato del protocolo: fecha_mensaje,emisor,mensaje,file, con el mensaje escapado */
+func (message *Message) format() string {
+
+	return message.date.Format("2006-01-02:15:04:05") + "," + message.author + "," + escapeField(string(message.content)) + "," + string(message.file)
+}
+
+/* Funcion
+ * Nombre: entry
+ * Descripcion: Retorna el mensaje como entrada del historial de un canal:
+ * id,respuestas,reacciones,fecha_mensaje,emisor,mensaje,file. Un mensaje eliminado queda con el mensaje y el file vacios */
+func (message *Message) entry() string {
+
+	return strconv.FormatUint(message.id, 10) + "," + strconv.Itoa(message.replyCount()) + "," + message.reactionCounts() + "," + message.format()
+}
+
+/* Funcion
+ * Nombre: replyCount
+ * Descripcion: Retorna la cantidad de respuestas del hilo del mensaje que no se han eliminado */
+func (message *Message) replyCount() int {
+
+	count := 0
+
+	for _, reply := range message.replies {
+		if !reply.isDeleted() {
+			count++
+		}
+	}
+	return count
+}
+
+/* Funcion
+ * Nombre: edit
+ * Descripcion: Cambia el contenido del mensaje guardando el anterior en su historial de ediciones
+ * @content: contenido nuevo */
+func (message *Message) edit(content []byte) {
+
+	written := message.date
+	if !message.edited.IsZero() {
+		written = message.edited
+	}
+
+	message.edits = append(message.edits, Revision{Content: string(message.content), Date: written})
+	message.content = content
+	message.edited = time.Now()
+}
+
+/* Funcion
+ * Nombre: remove
+ * Descripcion: Deja el mensaje como una marca de eliminado en el historial, sin su contenido, su archivo, sus
+ * ediciones ni sus reacciones. Conserva su identificador, fecha y autor para que el historial no cambie de posiciones */
+func (message *Message) remove() {
+
+	message.content, message.file, message.edits, message.reactions = nil, nil, nil, nil
+	message.deleted = time.Now()
+}
+
+/* Funcion
+ * Nombre: isDeleted
+ * Descripcion: Indica si el mensaje fue eliminado */
+func (message *Message) isDeleted() bool {
+
+	return !message.deleted.IsZero()
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/* Funcion
+ * Nombre: testClient
+ * Descripcion: Crea un cliente sin conexion para las pruebas del modelo
+ * @nickname: nombre registrado, vacio si el cliente no se registro */
+func testClient(port int, nickname string) *Client {
+
+	return &Client{address: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}, nickname: nickname}
+}
+
+/* Funcion
+ * Nombre: TestCanModify
+ * Descripcion: Solo el autor o un operador del canal modifican un mensaje, y solo los operadores los de las
+ * integraciones y del servidor. Un nombre sin contraseña solo modifica sus mensajes desde la misma conexion */
+func TestCanModify(t *testing.T) {
+
+	ana, bob, op := testClient(5001, "ana"), testClient(5002, "bob"), testClient(5003, "op")
+	anonymous := testClient(5004, "")
+	guest := testClient(5005, "ana") // Registro ana sin contraseña despues de que ana se desconecto
+
+	verified := func(client *Client) *Client { // Cliente con el nombre registrado con su contraseña
+		client.verified = true
+		return client
+	}
+	account, reconnected := verified(testClient(5006, "eva")), verified(testClient(5007, "eva"))
+
+	message := func(sender *Client, signed bool) *Message {
+		m := NewMessage(sender.address, sender.name(), []byte("hola"), nil)
+		m.verified = signed
+		return m
+	}
+
+	channel := NewChannel("general", "op")
+	channel.operators[op] = true
+
+	tests := []struct {
+		name    string
+		message *Message
+		client  *Client
+		allowed bool
+	}{
+		{"author", message(ana, false), ana, true},
+		{"other member", message(ana, false), bob, false},
+		{"operator", message(ana, false), op, true},
+		{"guest nickname on another connection", message(ana, false), guest, false}, // El nombre no basta sin contraseña
+		{"unregistered author", message(anonymous, false), anonymous, true},
+		{"unregistered other", message(anonymous, false), ana, false},
+		{"verified author", message(account, true), account, true},
+		{"verified author after reconnecting", message(account, true), reconnected, true},
+		{"unverified client with a verified name", message(account, true), testClient(5008, "eva"), false},
+		{"verified other", message(account, true), verified(testClient(5009, "bob")), false},
+		{"integration", NewMessage(integrationAddr{"ana"}, "ana", []byte("deploy"), nil), ana, false}, // Aunque el cliente use el mismo nombre
+		{"integration by operator", NewMessage(integrationAddr{"deploy"}, "deploy", []byte("deploy"), nil), op, true},
+		{"system", NewMessage(systemAddr{SystemPrefix + "ana"}, SystemPrefix+"ana", []byte("aviso"), nil), ana, false},
+		{"system by operator", NewMessage(systemAddr{SystemPrefix + "bot"}, SystemPrefix+"bot", []byte("aviso"), nil), op, true},
+	}
+
+	for _, test := range tests {
+		if got := channel.canModify(test.client, test.message); got != test.allowed {
+			t.Errorf("%s: canModify = %v, want %v", test.name, got, test.allowed)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestRemoveTombstone
+ * Descripcion: Un mensaje eliminado queda en el historial sin contenido, conservando su identificador, fecha y autor */
+func TestRemoveTombstone(t *testing.T) {
+
+	ana := testClient(5001, "ana")
+	channel := NewChannel("general", "ana")
+
+	for _, content := range []string{"uno", "dos", "tres"} {
+		channel.addMessage(NewMessage(ana.address, "ana", []byte(content), nil))
+	}
+
+	message := channel.findMessage(2)
+	message.edit([]byte("dos, editado"))
+	message.react("👍", "bob")
+	message.file = []byte("aGVsbG8=")
+	date := message.date
+
+	message.remove()
+
+	if !message.isDeleted() {
+		t.Fatal("removed message is not deleted")
+	}
+	if message.content != nil || message.file != nil || message.edits != nil || message.reactions != nil {
+		t.Fatalf("tombstone keeps content=%q file=%q edits=%v reactions=%v", message.content, message.file, message.edits, message.reactions)
+	}
+	if channel.findMessage(2) != message || len(channel.messages) != 3 || channel.findMessage(3).id != 3 {
+		t.Fatal("removing a message changed the history positions")
+	}
+
+	if want := "2,0,," + date.Format("2006-01-02:15:04:05") + ",ana,,"; message.entry() != want {
+		t.Errorf("tombstone entry = %q, want %q", message.entry(), want)
+	}
+
+	info := message.info()
+	if info.ID != 2 || info.Author != "ana" || info.Content != "" || info.Deleted == nil || len(info.Edits) != 0 || info.Reactions != nil {
+		t.Errorf("tombstone info = %+v", info)
+	}
+
+	if channel.threadRoot(2) != nil {
+		t.Error("threadRoot of a removed message is not nil, replies to it would be accepted")
+	}
+	if channel.threadRoot(1) == nil {
+		t.Error("threadRoot of a message next to a removed one is nil")
+	}
+}
+
+/* Funcion
+ * Nombre: TestThreadReplyCount
+ * Descripcion: Las respuestas se agregan al hilo de su raiz y la cantidad de respuestas no cuenta las eliminadas */
+func TestThreadReplyCount(t *testing.T) {
+
+	server := NewServer()
+	ana, bob := testClient(5001, "ana"), testClient(5002, "bob")
+	channel := NewChannel("general", "ana")
+
+	reply := func(parent uint64, client *Client) *Message {
+		root := channel.threadRoot(parent)
+		if root == nil {
+			t.Fatalf("threadRoot(%d) = nil", parent)
+		}
+		message := NewMessage(client.address, client.name(), []byte("respuesta"), nil)
+		channel.addMessage(message)
+		server.addReply(channel, root, message)
+		return message
+	}
+
+	root := NewMessage(ana.address, "ana", []byte("pregunta"), nil)
+	channel.addMessage(root)
+
+	first := reply(root.id, bob)
+	second := reply(first.id, ana) // Responder a una respuesta agrega la respuesta al hilo de la raiz
+	reply(root.id, bob)
+
+	if second.parent != root.id || len(first.replies) != 0 {
+		t.Fatalf("reply to a reply has parent %d and the reply has %d replies, threads must not nest", second.parent, len(first.replies))
+	}
+
+	steps := []struct {
+		name    string
+		remove  *Message
+		replies int
+	}{
+		{"three replies", nil, 3},
+		{"reply deleted", second, 2},
+		{"root deleted", root, 2}, // Los hilos de los mensajes eliminados conservan sus respuestas
+		{"first reply deleted", first, 1},
+	}
+
+	for _, step := range steps {
+
+		if step.remove != nil {
+			step.remove.remove()
+		}
+
+		if got := root.replyCount(); got != step.replies {
+			t.Errorf("%s: replyCount = %d, want %d", step.name, got, step.replies)
+		}
+		if got := root.info().Replies; got != step.replies {
+			t.Errorf("%s: info replies = %d, want %d", step.name, got, step.replies)
+		}
+		if want := "1," + strconv.Itoa(step.replies) + ","; !strings.HasPrefix(root.entry(), want) {
+			t.Errorf("%s: entry = %q, want prefix %q", step.name, root.entry(), want)
+		}
+	}
+
+	if len(root.replies) != 3 {
+		t.Errorf("thread keeps %d replies, want 3 including the deleted ones", len(root.replies))
+	}
+	if channel.threadRoot(first.id) != nil {
+		t.Error("threadRoot of a reply to a deleted root is not nil")
+	}
+}
+
+/* Funcion
+ * Nombre: TestEditNotice
+ * Descripcion: La notificacion EDIT escapa el contenido nuevo igual que las entradas de MSG y LIST_MSG */
+func TestEditNotice(t *testing.T) {
+
+	server := NewServer()
+	connection, peer := net.Pipe()
+	defer peer.Close()
+
+	client := NewClient(connection, server)
+	server.clients[client.address] = client
+
+	channel := NewChannel("general", "ana")
+	channel.clients[client] = true
+	channel.addMessage(NewMessage(client.address, client.name(), []byte("hola"), nil))
+	shardFor(server.shards, "general").channels[channelKey("general")] = channel
+
+	server.editMessage(client.address, "general", 1, []byte(`a;b,c\d`))
+
+	select {
+	case line := <-client.outbound:
+		if want := `EDIT general 1 a\;b\,c\\d` + "\n"; string(line) != want {
+			t.Fatalf("edit notice = %q, want %q", line, want)
+		}
+	default:
+		t.Fatal("no edit notice for the channel members")
+	}
+
+	if content := string(channel.findMessage(1).content); content != `a;b,c\d` {
+		t.Fatalf("edited content = %q, the message keeps the content without escapes", content)
+	}
+}
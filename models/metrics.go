@@ -0,0 +1,266 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Canal de la serie que suma los mensajes de los canales con modos, no es un nombre de canal valido
+const restrictedLabel = "(restricted)"
+
+// Limites superiores en segundos de los intervalos del histograma de latencia de los comandos
+var latencyBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Estructura para las metricas del servidor. Los contadores se actualizan desde cualquier rutina
+type Metrics struct {
+	connections     int64             // Conexiones aceptadas desde el inicio del servidor
+	errors          int64             // Respuestas ERROR enviadas a los clientes
+	bytesIn         int64             // Bytes recibidos de los clientes
+	bytesOut        int64             // Bytes escritos a los clientes
+	commands        map[string]*int64 // Solicitudes recibidas por comando, las llaves son fijas
+	latency         map[ID]*histogram // Latencia de los comandos desde que el cliente los envia hasta que se ejecutan
+	mu              sync.Mutex        // Protege los mensajes por canal
+	channelMessages map[string]int64  // Mensajes enviados a cada canal
+	messages        int64             // Mensajes enviados a todos los canales
+}
+
+// Estructura para un histograma acumulativo de duraciones
+type histogram struct {
+	mu     sync.Mutex
+	counts []int64 // Observaciones en cada intervalo de latencyBuckets, no acumuladas
+	count  int64   // Total de observaciones
+	sum    float64 // Suma de las observaciones en segundos
+}
+
+/* Funcion
+ * Nombre: newMetrics
+ * Descripcion: Funcion encargada de crear las metricas apartir de la estructura */
+func newMetrics() *Metrics {
+
+	metrics := &Metrics{
+		commands:        make(map[string]*int64),
+		latency:         make(map[ID]*histogram),
+		channelMessages: make(map[string]int64),
+	}
+
+	for name := range schemas { // Un contador por cada comando del protocolo
+		metrics.commands[name] = new(int64)
+	}
+
+	for id := ID(0); id < numCommands; id++ { // Un histograma por cada comando que ejecuta el servidor
+		metrics.latency[id] = &histogram{counts: make([]int64, len(latencyBuckets))}
+	}
+
+	return metrics
+}
+
+/* Funcion
+ * Nombre: command
+ * Descripcion: Cuenta una solicitud recibida de un comando del protocolo
+ * @name: nombre del comando */
+func (metrics *Metrics) command(name string) {
+
+	if counter, ok := metrics.commands[name]; ok { // Los comandos desconocidos se cuentan como errores
+		atomic.AddInt64(counter, 1)
+	}
+}
+
+/* Funcion
+ * Nombre: observe
+ * Descripcion: Registra en el histograma de un comando el tiempo desde que el cliente lo envio
+ * @cmd: comando ejecutado */
+func (metrics *Metrics) observe(cmd Command) {
+
+	h, ok := metrics.latency[cmd.id]
+
+	if !ok || cmd.received.IsZero() {
+		return
+	}
+
+	seconds := time.Since(cmd.received).Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+/* Funcion
+ * Nombre: message
+ * Descripcion: Cuenta un mensaje enviado a un canal
+ * @channelName: nombre del canal */
+func (metrics *Metrics) message(channelName string) {
+
+	atomic.AddInt64(&metrics.messages, 1)
+
+	metrics.mu.Lock()
+	metrics.channelMessages[channelName]++
+	metrics.mu.Unlock()
+}
+
+/* Funcion
+ * Nombre: forget
+ * Descripcion: Descarta las series de un canal eliminado, asi las metricas no crecen con cada canal que existio
+ * @channelName: nombre del canal */
+func (metrics *Metrics) forget(channelName string) {
+
+	metrics.mu.Lock()
+	delete(metrics.channelMessages, channelName)
+	metrics.mu.Unlock()
+}
+
+/* Funcion
+ * Nombre: totals
+ * Descripcion: Retorna los contadores acumulados de las metricas */
+func (metrics *Metrics) totals() Totals {
+
+	totals := Totals{
+		Messages:      atomic.LoadInt64(&metrics.messages),
+		Errors:        atomic.LoadInt64(&metrics.errors),
+		ReceivedBytes: atomic.LoadInt64(&metrics.bytesIn),
+		SentBytes:     atomic.LoadInt64(&metrics.bytesOut),
+	}
+
+	for _, counter := range metrics.commands {
+		totals.Commands += atomic.LoadInt64(counter)
+	}
+	return totals
+}
+
+/* Funcion
+ * Nombre: WriteMetrics
+ * Descripcion: Escribe las metricas del servidor en el formato de texto de Prometheus
+ * @writer: destino de las metricas
+ * return: @error: err si fallo la escritura */
+func (server *Server) WriteMetrics(writer io.Writer) error {
+
+	metrics := server.metrics
+	b := &strings.Builder{}
+
+	server.mu.RLock()
+	clients := len(server.clients)
+	users := len(server.nicknames)
+	server.mu.RUnlock()
+
+	channels := 0
+	restricted := make(map[string]bool) // Canales con modos, sus nombres no se publican
+	for _, shard := range server.shards {
+		shard.mu.RLock()
+		channels += len(shard.channels)
+		for _, channel := range shard.channels {
+			if channel.restricted() {
+				restricted[channel.name] = true
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	queue := server.QueueStats()
+
+	writeMetric(b, "socketcam_connections", "gauge", "Clients connected to the server", int64(clients))
+	writeMetric(b, "socketcam_connections_total", "counter", "Connections accepted since the server started", atomic.LoadInt64(&metrics.connections))
+	writeMetric(b, "socketcam_registered_clients", "gauge", "Connected clients with a registered nickname", int64(users))
+	writeMetric(b, "socketcam_channels", "gauge", "Channels in the server", int64(channels))
+	writeMetric(b, "socketcam_errors_total", "counter", "ERROR responses sent to clients", atomic.LoadInt64(&metrics.errors))
+	writeMetric(b, "socketcam_received_bytes_total", "counter", "Bytes received from clients", atomic.LoadInt64(&metrics.bytesIn))
+	writeMetric(b, "socketcam_sent_bytes_total", "counter", "Bytes written to clients", atomic.LoadInt64(&metrics.bytesOut))
+	writeMetric(b, "socketcam_queued_responses", "gauge", "Responses waiting in the outbound queues", queue.Queued)
+	writeMetric(b, "socketcam_dropped_responses_total", "counter", "Responses dropped because of full outbound queues", queue.Dropped)
+	writeMetric(b, "socketcam_slow_consumers_total", "counter", "Clients disconnected for not reading their responses", queue.SlowConsumers)
+
+	// Solicitudes por comando
+	names := make([]string, 0, len(metrics.commands))
+	for name := range metrics.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(b, "socketcam_commands_total", "counter", "Requests received by command")
+	for _, name := range names {
+		fmt.Fprintf(b, "socketcam_commands_total{command=\"%s\"} %d\n", name, atomic.LoadInt64(metrics.commands[name]))
+	}
+
+	// Mensajes por canal
+	metrics.mu.Lock()
+	channelNames := make([]string, 0, len(metrics.channelMessages))
+	counts := make(map[string]int64, len(metrics.channelMessages))
+	for name, count := range metrics.channelMessages {
+		channelNames = append(channelNames, name)
+		counts[name] = count
+	}
+	metrics.mu.Unlock()
+	sort.Strings(channelNames)
+
+	// Los canales con modos se ocultan de LIST_CHN y de la API, sus mensajes se suman en una sola serie
+	hidden := int64(0)
+
+	writeHeader(b, "socketcam_channel_messages_total", "counter", "Messages sent by channel")
+	for _, name := range channelNames {
+		if restricted[name] {
+			hidden += counts[name]
+			continue
+		}
+		fmt.Fprintf(b, "socketcam_channel_messages_total{channel=\"%s\"} %d\n", escapeLabel(name), counts[name])
+	}
+	if hidden > 0 {
+		fmt.Fprintf(b, "socketcam_channel_messages_total{channel=\"%s\"} %d\n", restrictedLabel, hidden)
+	}
+
+	// Latencia de los comandos
+	writeHeader(b, "socketcam_command_duration_seconds", "histogram", "Time from a client sending a command until the server executes it")
+	for id := ID(0); id < numCommands; id++ {
+
+		h := metrics.latency[id]
+
+		h.mu.Lock()
+		cumulative := int64(0)
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "socketcam_command_duration_seconds_bucket{command=\"%s\",le=\"%g\"} %d\n", id, bound, cumulative)
+		}
+		fmt.Fprintf(b, "socketcam_command_duration_seconds_bucket{command=\"%s\",le=\"+Inf\"} %d\n", id, h.count)
+		fmt.Fprintf(b, "socketcam_command_duration_seconds_sum{command=\"%s\"} %g\n", id, h.sum)
+		fmt.Fprintf(b, "socketcam_command_duration_seconds_count{command=\"%s\"} %d\n", id, h.count)
+		h.mu.Unlock()
+	}
+
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
+
+/* Funcion
+ * Nombre: writeHeader
+ * Descripcion: Escribe la ayuda y el tipo de una metrica */
+func writeHeader(b *strings.Builder, name, kind, help string) {
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+/* Funcion
+ * Nombre: writeMetric
+ * Descripcion: Escribe una metrica sin etiquetas con su ayuda y su tipo */
+func writeMetric(b *strings.Builder, name, kind, help string, value int64) {
+
+	writeHeader(b, name, kind, help)
+	fmt.Fprintf(b, "%s %d\n", name, value)
+}
+
+/* Funcion
+ * Nombre: escapeLabel
+ * Descripcion: Escapa el valor de una etiqueta segun el formato de texto de Prometheus */
+func escapeLabel(value string) string {
+
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+/* Funcion
+ * Nombre: TestMetricsRestrictedChannels
+ * Descripcion: Las metricas por canal no publican los nombres de los canales con modos */
+func TestMetricsRestrictedChannels(t *testing.T) {
+
+	server := NewServer()
+
+	for _, name := range []string{"general", "secreto", "equipo"} {
+		shardFor(server.shards, name).channels[channelKey(name)] = NewChannel(name, "ana")
+		server.metrics.message(name)
+	}
+	server.metrics.message("equipo")
+
+	if channel, ok := server.channel("secreto"); ok {
+		channel.private = true
+	}
+	if channel, ok := server.channel("equipo"); ok {
+		channel.inviteOnly = true
+	}
+
+	b := &strings.Builder{}
+	if err := server.WriteMetrics(b); err != nil {
+		t.Fatal(err)
+	}
+	metrics := b.String()
+
+	for _, line := range []string{
+		`socketcam_channel_messages_total{channel="general"} 1`,
+		`socketcam_channel_messages_total{channel="(restricted)"} 3`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("metrics without %s", line)
+		}
+	}
+	for _, name := range []string{"secreto", "equipo"} {
+		if strings.Contains(metrics, name) {
+			t.Errorf("metrics show the restricted channel %s", name)
+		}
+	}
+}
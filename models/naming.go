@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Longitud maxima del nombre de un canal, en caracteres
+const MaxChannelName = 32
+
+// Prefijos reservados para las identidades del propio servidor, ningun canal ni cliente puede usarlos,
+// asi un mensaje con uno de ellos no puede venir de un cliente que suplante a otro
+const (
+	SystemPrefix = "sys-"   // Mensajes de los procesos del servidor, como los bots locales
+	AdminPrefix  = "admin-" // Mensajes publicados por un administrador desde la API
+)
+
+var reservedPrefixes = []string{SystemPrefix, AdminPrefix}
+
+// Escrituras que se usan juntas en un mismo idioma, cuentan como la escritura Han al comparar las de un nombre
+var scriptGroups = map[string]string{"Hiragana": "Han", "Katakana": "Han", "Hangul": "Han", "Bopomofo": "Han"}
+
+/* Funcion
+ * Nombre: normalizeChannelName
+ * Descripcion: Normaliza el nombre de un canal a su forma Unicode NFKC
+ * @name: nombre del canal escrito por el cliente */
+func normalizeChannelName(name string) string {
+
+	return norm.NFKC.String(strings.TrimSpace(name))
+}
+
+/* Funcion
+ * Nombre: channelKey
+ * Descripcion: Retorna la llave con la que se guarda un canal en el servidor, los nombres
+ * que solo se diferencian en mayusculas o en su representacion Unicode comparten la misma llave
+ * @name: nombre del canal */
+func channelKey(name string) string {
+
+	return cases.Fold().String(normalizeChannelName(name))
+}
+
+/* Funcion
+ * Nombre: validateChannelName
+ * Descripcion: Valida que el nombre de un canal cumpla las reglas de nombrado: letras, digitos, '-', '_' y '.',
+ * maximo MaxChannelName caracteres y sin prefijos reservados
+ * @name: nombre del canal normalizado
+ * return: @error: nil si el nombre es valido, err con la razon si no lo es */
+func validateChannelName(name string) error {
+
+	if name == "" { // Manejamos que el nombre no sea vacio
+		return errors.New("empty channel name")
+	}
+
+	if utf8.RuneCountInString(name) > MaxChannelName { // Manejamos la longitud maxima
+		return errors.New("channel name longer than " + strconv.Itoa(MaxChannelName) + " characters")
+	}
+
+	for _, r := range name { // Manejamos que solo tenga caracteres permitidos
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+			return errors.New("invalid character " + strconv.QuoteRune(r) + " in channel name")
+		}
+	}
+
+	if err := checkScripts(name); err != nil { // Manejamos que no mezcle letras parecidas de distintas escrituras
+		return errors.New("channel name " + err.Error())
+	}
+
+	if prefix, ok := reservedPrefix(name); ok { // Manejamos que no use un prefijo reservado para el sistema
+		return errors.New("channel prefix " + prefix + " is reserved")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: validateNickname
+ * Descripcion: Valida que el nombre de un cliente no rompa el formato de las respuestas, no use un prefijo
+ * reservado y no mezcle escrituras, para que no pueda imitar el nombre de otro cliente
+ * @nickname: nombre a registrar
+ * return: @error: nil si el nombre es valido, err con la razon si no lo es */
+func validateNickname(nickname string) error {
+
+	if nickname == "" {
+		return errors.New("empty nickname")
+	}
+
+	for _, r := range nickname { // Manejamos que no tenga separadores del protocolo ni caracteres de control
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == ',' || r == ';' {
+			return errors.New("invalid nickname " + nickname)
+		}
+	}
+
+	if err := checkScripts(nickname); err != nil {
+		return errors.New("nickname " + err.Error())
+	}
+
+	if prefix, ok := reservedPrefix(nickname); ok {
+		return errors.New("nickname prefix " + prefix + " is reserved")
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: reservedPrefix
+ * Descripcion: Busca un prefijo reservado al inicio de un nombre, sin importar mayusculas ni su representacion Unicode
+ * @name: nombre de un canal o un cliente
+ * return: @string: prefijo encontrado
+ *         @bool: true si el nombre usa un prefijo reservado */
+func reservedPrefix(name string) (string, bool) {
+
+	key := channelKey(name)
+
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+/* Funcion
+ * Nombre: checkScripts
+ * Descripcion: Comprueba que las letras de un nombre sean de una sola escritura, como latina o cirilica, asi un
+ * nombre no se confunde con otro usando letras que se ven iguales, como la 'а' cirilica en lugar de la 'a' latina.
+ * Los digitos y signos comunes a todas las escrituras no cuentan
+ * @name: nombre a comprobar
+ * return: @error: nil si usa una sola escritura, err con las dos primeras escrituras que mezcla */
+func checkScripts(name string) error {
+
+	first := ""
+
+	for _, r := range name {
+
+		script := scriptOf(r)
+
+		if script == "" {
+			continue
+		}
+
+		if first == "" {
+			first = script
+		} else if script != first {
+			return errors.New("mixes " + first + " and " + script + " scripts")
+		}
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: scriptOf
+ * Descripcion: Retorna la escritura de un caracter segun Unicode, agrupada segun scriptGroups
+ * @r: caracter
+ * return: @string: nombre de la escritura, vacio si el caracter es comun a todas */
+func scriptOf(r rune) string {
+
+	if unicode.Is(unicode.Latin, r) { // La mayoria de los nombres son latinos, evitamos recorrer las tablas
+		return "Latin"
+	}
+
+	for name, table := range unicode.Scripts {
+
+		if name == "Common" || name == "Inherited" || !unicode.Is(table, r) {
+			continue
+		}
+
+		if group, ok := scriptGroups[name]; ok {
+			return group
+		}
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+/* Funcion
+ * Nombre: TestNormalizeChannelName
+ * Descripcion: Los nombres que solo cambian en su representacion Unicode o en mayusculas comparten la llave */
+func TestNormalizeChannelName(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		normalized string
+		key        string
+	}{
+		{"general", "general", "general"},
+		{"  General ", "General", "general"},
+		{"ｇｅｎｅｒａｌ", "general", "general"}, // Ancho completo
+		{"cafe\u0301", "café", "café"},    // e seguida de acento combinante
+		{"ＤＥＶ-２", "DEV-2", "dev-2"},       // Ancho completo con digito
+		{"Straße", "Straße", "strasse"},   // El plegado de mayusculas expande la ß
+		{"ΣΟΦΙΑ", "ΣΟΦΙΑ", "σοφια"},       // Mayusculas griegas
+	}
+
+	for _, test := range tests {
+
+		if got := normalizeChannelName(test.name); got != test.normalized {
+			t.Errorf("normalizeChannelName(%q) = %q, want %q", test.name, got, test.normalized)
+		}
+		if got := channelKey(test.name); got != test.key {
+			t.Errorf("channelKey(%q) = %q, want %q", test.name, got, test.key)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestValidateChannelName
+ * Descripcion: Reglas de nombrado de los canales, con el inicio del error esperado o vacio si el nombre es valido */
+func TestValidateChannelName(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{"general", ""},
+		{"dev-2.old_team", ""},
+		{"café", ""},
+		{"日本語チャンネル", ""}, // Han y Katakana se usan juntas
+		{"한국어채널", ""},    // Hangul
+		{"канал", ""},    // Solo cirilico
+		{strings.Repeat("a", MaxChannelName), ""},
+		{"", "empty channel name"},
+		{strings.Repeat("a", MaxChannelName+1), "channel name longer than"},
+		{"two words", "invalid character ' '"},
+		{"a,b", "invalid character ','"},
+		{"pаypal", "channel name mixes Latin and Cyrillic scripts"}, // 'а' cirilica
+		{"devΑ", "channel name mixes Latin and Greek scripts"},      // 'Α' griega
+		{"sys-log", "channel prefix sys- is reserved"},
+		{"SYS-log", "channel prefix sys- is reserved"},
+		{"admin-ops", "channel prefix admin- is reserved"},
+		{"system", ""}, // Solo el prefijo completo esta reservado
+	}
+
+	for _, test := range tests {
+
+		name := normalizeChannelName(test.name)
+		err := validateChannelName(name)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("validateChannelName(%q) = %v, want nil", name, err)
+		case test.err != "" && (err == nil || !strings.HasPrefix(err.Error(), test.err)):
+			t.Errorf("validateChannelName(%q) = %v, want %q", name, err, test.err)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestValidateNickname
+ * Descripcion: Reglas de nombrado de los clientes, con el inicio del error esperado o vacio si el nombre es valido */
+func TestValidateNickname(t *testing.T) {
+
+	tests := []struct {
+		nickname string
+		err      string
+	}{
+		{"ana", ""},
+		{"ana.dev[2]", ""},
+		{"josé", ""},
+		{"", "empty nickname"},
+		{"ana maria", "invalid nickname"},
+		{"ana\tmaria", "invalid nickname"},
+		{"ana,bob", "invalid nickname"},
+		{"ana;bob", "invalid nickname"},
+		{"аna", "nickname mixes Cyrillic and Latin scripts"},
+		{"sys-helper", "nickname prefix sys- is reserved"},
+		{"Admin-root", "nickname prefix admin- is reserved"},
+	}
+
+	for _, test := range tests {
+
+		err := validateNickname(test.nickname)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("validateNickname(%q) = %v, want nil", test.nickname, err)
+		case test.err != "" && (err == nil || !strings.HasPrefix(err.Error(), test.err)):
+			t.Errorf("validateNickname(%q) = %v, want %q", test.nickname, err, test.err)
+		}
+	}
+}
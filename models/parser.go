@@ -0,0 +1,226 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Separador de argumentos en una solicitud del protocolo
+var separator = []byte(";;")
+
+// Error informado al cliente cuando una solicitud supera el tamaño maximo
+var errRequestTooLarge = errors.New("request too large")
+
+// Caracter de escape, permite enviar el separador o el propio escape dentro de un argumento
+const escape = '\\'
+
+// Tamaño maximo de la etiqueta de una solicitud
+const maxTagSize = 64
+
+// Escapes de los campos de texto libre en las respuestas, asi una coma o un punto y coma del contenido no se
+// confunde con los separadores de los campos y las entradas
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`)
+
+// Estructura para el esquema de argumentos de un comando
+type schema struct {
+	args     []string // Nombres de los argumentos en orden
+	required int      // Cantidad de argumentos obligatorios, los siguientes son opcionales
+}
+
+// Esquemas de los comandos disponibles en el protocolo personalizado
+var schemas = map[string]schema{
+	"REG":      {args: []string{"nickname", "password"}, required: 1},
+	"JOIN":     {args: []string{"nameChannel", "password"}, required: 1},
+	"LEAVE":    {args: []string{"nameChannel"}, required: 1},
+	"CREATE":   {args: []string{"nameChannel"}, required: 1},
+	"LIST_CHN": {args: []string{}, required: 0},
+	"MSG":      {args: []string{"nameChannel", "messageContent", "file", "parentId"}, required: 2},
+	"LIST_MSG": {args: []string{"nameChannel"}, required: 1},
+	"LIST_USR": {args: []string{"nameChannel"}, required: 1},
+	"INVITE":   {args: []string{"nameChannel", "client"}, required: 2},
+	"MODE":     {args: []string{"nameChannel", "mode", "password"}, required: 2},
+	"TOPIC":    {args: []string{"nameChannel", "topic", "description"}, required: 1},
+	"EDIT":     {args: []string{"nameChannel", "messageId", "messageContent"}, required: 3},
+	"DELETE":   {args: []string{"nameChannel", "messageId"}, required: 2},
+	"THREAD":   {args: []string{"nameChannel", "messageId"}, required: 2},
+	"REACT":    {args: []string{"nameChannel", "messageId", "reaction"}, required: 3},
+	"UNREACT":  {args: []string{"nameChannel", "messageId", "reaction"}, required: 3},
+	"DM":       {args: []string{"nickname", "messageContent", "file"}, required: 2},
+	"LIST_DM":  {args: []string{"nickname"}, required: 0},
+	"PING":     {args: []string{"token"}, required: 0},
+	"PONG":     {args: []string{"token"}, required: 0},
+}
+
+/* Funcion
+ * Nombre: usage
+ * Descripcion: Retorna la forma de uso de un comando segun su esquema, por ejemplo: MSG [nameChannel];;[messageContent];;[file]
+ * @cmd: nombre del comando */
+func (s schema) usage(cmd string) string {
+
+	args := make([]string, len(s.args))
+
+	for i, name := range s.args {
+		if i < s.required {
+			args[i] = "[" + name + "]"
+		} else {
+			args[i] = "[" + name + "?]"
+		}
+	}
+	return strings.TrimSpace(cmd + " " + strings.Join(args, string(separator)))
+}
+
+/* Funcion
+ * Nombre: parseRequest
+ * Descripcion: Analiza una solicitud del cliente con el formato COMANDO arg;;arg;;arg, validando los argumentos
+ * contra el esquema del comando. Los argumentos opcionales que no se envian quedan en nil
+ * @request: solicitud del cliente en bytes
+ * return: @string: nombre del comando en mayusculas, vacio si la solicitud esta vacia
+ *         @[][]byte: argumentos del comando, uno por cada argumento del esquema
+ *         @error: nil si la solicitud es valida, err describiendo el error de sintaxis si no lo es */
+func parseRequest(request []byte) (string, [][]byte, error) {
+
+	request = bytes.TrimSpace(request)
+
+	if len(request) == 0 { // Las solicitudes vacias se ignoran
+		return "", nil, nil
+	}
+
+	cmd, rest := request, []byte(nil) // El comando es el primer corte de la solicitud segun el primer espacio en blanco
+
+	if i := bytes.IndexAny(request, " \t"); i >= 0 {
+		cmd, rest = request[:i], bytes.TrimSpace(request[i+1:])
+	}
+
+	name := strings.ToUpper(string(cmd))
+	s, ok := schemas[name]
+
+	if !ok { // Manejamos que el comando exista en el protocolo
+		return name, nil, errors.New("unknown command " + name)
+	}
+
+	args, err := splitArgs(rest)
+
+	if err != nil {
+		return name, nil, errors.New("syntax error: " + err.Error() + ", usage: " + s.usage(name))
+	}
+
+	if len(args) > len(s.args) { // Manejamos que no se envien argumentos de mas
+		return name, nil, errors.New("syntax error: too many arguments, usage: " + s.usage(name))
+	}
+
+	for i := 0; i < s.required; i++ { // Manejamos que los argumentos obligatorios no esten vacios
+		if i >= len(args) || len(args[i]) == 0 {
+			return name, nil, errors.New("syntax error: missing " + s.args[i] + ", usage: " + s.usage(name))
+		}
+	}
+
+	for len(args) < len(s.args) { // Completamos los argumentos opcionales que no se enviaron
+		args = append(args, nil)
+	}
+
+	for i := range args { // Los argumentos opcionales vacios se tratan como no enviados
+		if len(args[i]) == 0 {
+			args[i] = nil
+		}
+	}
+	return name, args, nil
+}
+
+/* Funcion
+ * Nombre: parseMessageID
+ * Descripcion: Convierte el identificador de un mensaje enviado como argumento, los identificadores empiezan en 1
+ * @arg: argumento del cliente
+ * return: @uint64: identificador del mensaje
+ *         @error: err si no es un identificador valido */
+func parseMessageID(arg []byte) (uint64, error) {
+
+	id, err := strconv.ParseUint(string(arg), 10, 64)
+
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid message id " + strconv.Quote(string(arg)))
+	}
+	return id, nil
+}
+
+/* Funcion
+ * Nombre: splitTag
+ * Descripcion: Separa la etiqueta opcional de una solicitud con el formato @etiqueta COMANDO args. Al terminar de
+ * procesar una solicitud con etiqueta el servidor responde DONE [etiqueta], asi el cliente sabe que respuestas le
+ * corresponden aunque el comando no tenga respuesta
+ * @request: solicitud del cliente en bytes
+ * return: @string: etiqueta, vacia si la solicitud no tiene
+ *         @[]byte: solicitud sin la etiqueta
+ *         @error: err si la etiqueta esta vacia o es muy larga */
+func splitTag(request []byte) (string, []byte, error) {
+
+	trimmed := bytes.TrimLeft(request, " \t")
+
+	if len(trimmed) == 0 || trimmed[0] != '@' { // La solicitud no tiene etiqueta
+		return "", request, nil
+	}
+
+	tag, rest := trimmed[1:], []byte(nil)
+
+	if i := bytes.IndexAny(tag, " \t\r\n"); i >= 0 {
+		tag, rest = tag[:i], tag[i:]
+	}
+
+	if len(tag) == 0 || len(tag) > maxTagSize {
+		return "", rest, errors.New("invalid tag, usage: @[tag] COMMAND with up to " + strconv.Itoa(maxTagSize) + " characters")
+	}
+	return string(tag), rest, nil
+}
+
+/* Funcion
+ * Nombre: splitArgs
+ * Descripcion: Separa los argumentos de una solicitud segun el separador ;; resolviendo los escapes:
+ * \; se toma como ; literal y \\ como \ literal
+ * @args: argumentos escritos por el cliente
+ * return: @[][]byte: argumentos separados, vacio si no se enviaron argumentos
+ *         @error: nil si los escapes son validos, err si no lo son */
+func splitArgs(args []byte) ([][]byte, error) {
+
+	result := make([][]byte, 0)
+
+	if len(args) == 0 {
+		return result, nil
+	}
+
+	current := make([]byte, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+
+		switch {
+
+		case args[i] == escape: // El siguiente caracter se toma literal
+			if i+1 >= len(args) {
+				return nil, errors.New("dangling escape at end of request")
+			}
+			if args[i+1] != escape && args[i+1] != separator[0] {
+				return nil, errors.New("invalid escape \\" + string(args[i+1]))
+			}
+			i++
+			current = append(current, args[i])
+
+		case bytes.HasPrefix(args[i:], separator): // Fin del argumento actual
+			result = append(result, current)
+			current = make([]byte, 0, len(args)-i)
+			i += len(separator) - 1
+
+		default:
+			current = append(current, args[i])
+		}
+	}
+	return append(result, current), nil
+}
+
+/* Funcion
+ * Nombre: escapeField
+ * Descripcion: Escapa un campo de texto libre de una respuesta: \ como \\, ; como \; y , como \,
+ * @field: contenido, tema o descripcion */
+func escapeField(field string) string {
+
+	return fieldEscaper.Replace(field)
+}
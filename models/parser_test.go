@@ -0,0 +1,184 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* Funcion
+ * Nombre: strs
+ * Descripcion: Convierte argumentos a texto para compararlos y mostrarlos en los errores */
+func strs(args [][]byte) []string {
+
+	result := make([]string, len(args))
+	for i, arg := range args {
+		result[i] = string(arg)
+	}
+	return result
+}
+
+/* Funcion
+ * Nombre: TestSplitArgs
+ * Descripcion: Separacion de los argumentos por ;; con los escapes \; y \\, con el error esperado o vacio */
+func TestSplitArgs(t *testing.T) {
+
+	tests := []struct {
+		args string
+		want []string
+		err  string
+	}{
+		{``, []string{}, ""},
+		{`general`, []string{"general"}, ""},
+		{`general;;hola`, []string{"general", "hola"}, ""},
+		{`general;;`, []string{"general", ""}, ""},
+		{`;;hola`, []string{"", "hola"}, ""},
+		{`a;b`, []string{"a;b"}, ""}, // Un ; solo no separa
+		{`a;;;b`, []string{"a", ";b"}, ""},
+		{`a\;;b`, []string{"a;;b"}, ""}, // El ; escapado no inicia un separador
+		{`a\;\;b`, []string{"a;;b"}, ""},
+		{`a\\;;b`, []string{`a\`, "b"}, ""}, // El escape escapado no escapa el separador
+		{`c:\\dir`, []string{`c:\dir`}, ""},
+		{`hola\`, nil, "dangling escape at end of request"},
+		{`a;;b\\\`, nil, "dangling escape at end of request"},
+		{`a\nb`, nil, `invalid escape \n`},
+		{`a\,b`, nil, `invalid escape \,`}, // La coma solo se escapa en las respuestas
+	}
+
+	for _, test := range tests {
+
+		got, err := splitArgs([]byte(test.args))
+
+		switch {
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("splitArgs(%q) error = %v, want %q", test.args, err, test.err)
+		case test.err == "" && err != nil:
+			t.Errorf("splitArgs(%q) error = %v", test.args, err)
+		case test.err == "" && !reflect.DeepEqual(strs(got), test.want):
+			t.Errorf("splitArgs(%q) = %q, want %q", test.args, strs(got), test.want)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestSplitTag
+ * Descripcion: Separacion de la etiqueta opcional de una solicitud */
+func TestSplitTag(t *testing.T) {
+
+	tests := []struct {
+		request string
+		tag     string
+		rest    string
+		err     bool
+	}{
+		{"MSG general;;hola", "", "MSG general;;hola", false},
+		{"@1 MSG general;;hola", "1", " MSG general;;hola", false},
+		{"  @abc\tPING", "abc", "\tPING", false},
+		{"@solo", "solo", "", false},
+		{"MSG general;;@ana", "", "MSG general;;@ana", false}, // Solo al inicio es una etiqueta
+		{"@" + strings.Repeat("t", maxTagSize) + " PING", strings.Repeat("t", maxTagSize), " PING", false},
+		{"@", "", "", true},
+		{"@ PING", "", "", true},
+		{"@" + strings.Repeat("t", maxTagSize+1) + " PING", "", "", true},
+	}
+
+	for _, test := range tests {
+
+		tag, rest, err := splitTag([]byte(test.request))
+
+		if (err != nil) != test.err {
+			t.Errorf("splitTag(%q) error = %v, want error %v", test.request, err, test.err)
+			continue
+		}
+		if !test.err && (tag != test.tag || string(rest) != test.rest) {
+			t.Errorf("splitTag(%q) = %q, %q, want %q, %q", test.request, tag, rest, test.tag, test.rest)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestParseRequest
+ * Descripcion: Analisis de las solicitudes contra los esquemas, los opcionales no enviados o vacios quedan en nil */
+func TestParseRequest(t *testing.T) {
+
+	msgUsage := ", usage: MSG [nameChannel];;[messageContent];;[file?];;[parentId?]"
+
+	tests := []struct {
+		request string
+		cmd     string
+		args    [][]byte
+		err     string
+	}{
+		{"", "", nil, ""},
+		{"   \r\n", "", nil, ""},
+		{"LIST_CHN", "LIST_CHN", [][]byte{}, ""},
+		{"  msg general;;hola \r\n", "MSG", [][]byte{[]byte("general"), []byte("hola"), nil, nil}, ""},
+		{"MSG\tgeneral;;hola", "MSG", [][]byte{[]byte("general"), []byte("hola"), nil, nil}, ""},
+		{"MSG general;;hola;;;;7", "MSG", [][]byte{[]byte("general"), []byte("hola"), nil, []byte("7")}, ""},
+		{`MSG general;;a\;;;b`, "MSG", [][]byte{[]byte("general"), []byte("a;"), []byte("b"), nil}, ""},
+		{`MSG general;;hola\\`, "MSG", [][]byte{[]byte("general"), []byte(`hola\`), nil, nil}, ""},
+		{"REG ana;;", "REG", [][]byte{[]byte("ana"), nil}, ""},
+		{"TOPIC general;;tema;;hola, mundo", "TOPIC", [][]byte{[]byte("general"), []byte("tema"), []byte("hola, mundo")}, ""},
+		{"FOO bar", "FOO", nil, "unknown command FOO"},
+		{"MSG general", "MSG", nil, "syntax error: missing messageContent" + msgUsage},
+		{"MSG general;;", "MSG", nil, "syntax error: missing messageContent" + msgUsage},
+		{"MSG ;;hola", "MSG", nil, "syntax error: missing nameChannel" + msgUsage},
+		{`MSG general;;hola\`, "MSG", nil, "syntax error: dangling escape at end of request" + msgUsage},
+		{`MSG general;;\hola`, "MSG", nil, `syntax error: invalid escape \h` + msgUsage},
+		{"MSG a;;b;;c;;1;;e", "MSG", nil, "syntax error: too many arguments" + msgUsage},
+		{"PING a;;b", "PING", nil, "syntax error: too many arguments, usage: PING [token?]"},
+		{"LIST_CHN ;;", "LIST_CHN", nil, "syntax error: too many arguments, usage: LIST_CHN"},
+	}
+
+	for _, test := range tests {
+
+		cmd, args, err := parseRequest([]byte(test.request))
+
+		switch {
+		case cmd != test.cmd:
+			t.Errorf("parseRequest(%q) command = %q, want %q", test.request, cmd, test.cmd)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("parseRequest(%q) error = %v, want %q", test.request, err, test.err)
+		case test.err == "" && err != nil:
+			t.Errorf("parseRequest(%q) error = %v", test.request, err)
+		case test.err == "" && !reflect.DeepEqual(args, test.args):
+			t.Errorf("parseRequest(%q) args = %q, want %q", test.request, args, test.args)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestParseMessageID
+ * Descripcion: Los identificadores de los mensajes son enteros positivos */
+func TestParseMessageID(t *testing.T) {
+
+	for arg, want := range map[string]uint64{"1": 1, "42": 42, "0": 0, "-1": 0, "x": 0, "": 0, "1.5": 0} {
+
+		id, err := parseMessageID([]byte(arg))
+
+		if id != want || (err == nil) != (want != 0) {
+			t.Errorf("parseMessageID(%q) = %d, %v, want %d", arg, id, err, want)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestEscapeField
+ * Descripcion: Los separadores de las respuestas dentro del texto libre se escapan */
+func TestEscapeField(t *testing.T) {
+
+	tests := map[string]string{
+		"hola":           "hola",
+		"hola, mundo":    `hola\, mundo`,
+		"a;b":            `a\;b`,
+		`c:\dir`:         `c:\\dir`,
+		`\;,`:            `\\\;\,`,
+		"JOIN general;;": `JOIN general\;\;`,
+	}
+
+	for field, want := range tests {
+		if got := escapeField(field); got != want {
+			t.Errorf("escapeField(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Politicas cuando la cola de salida de un cliente se llena
+const (
+	DropOldest = "drop-oldest" // Se descarta la respuesta mas antigua de la cola
+	Disconnect = "disconnect"  // Se desconecta al cliente por ser demasiado lento
+)
+
+// Tamaño de la cola de salida cuando la configuracion no lo indica, sin cola el servidor descartaria las respuestas
+const DefaultQueueSize = 256
+
+// Tiempo maximo para escribir las respuestas pendientes al cerrar una conexion, si WriteTimeout no lo limita
+const closeTimeout = 5 * time.Second
+
+// Estructura para las metricas de las colas de salida de todos los clientes
+type QueueStats struct {
+	Queued        int64 `json:"queued"`        // Respuestas en cola sin escribir, entre todos los clientes
+	Dropped       int64 `json:"dropped"`       // Respuestas descartadas por colas llenas
+	SlowConsumers int64 `json:"slowConsumers"` // Clientes desconectados por no leer sus respuestas
+}
+
+/* Funcion
+ * Nombre: enqueue
+ * Descripcion: Agrega una linea a la cola de salida del cliente sin bloquear. Si la cola esta llena
+ * aplica la politica configurada: descartar la respuesta mas antigua o desconectar al cliente
+ * @line: linea a escribir
+ * return: @bool: true si la linea quedo en la cola */
+func (client *Client) enqueue(line []byte) bool {
+
+	select {
+	case <-client.done: // La conexion ya termino
+		return false
+	default:
+	}
+
+	select {
+	case client.outbound <- line:
+		atomic.AddInt64(&client.stats.Queued, 1)
+		return true
+	default: // La cola esta llena
+	}
+
+	if client.config.QueuePolicy == Disconnect { // El cliente no lee sus respuestas, lo desconectamos
+		atomic.AddInt64(&client.stats.SlowConsumers, 1)
+		client.log().Warn("disconnecting slow consumer", "queue", len(client.outbound))
+		client.connection.Close()
+		return false
+	}
+
+	select { // Descartamos la respuesta mas antigua para hacer espacio
+	case <-client.outbound:
+		atomic.AddInt64(&client.stats.Queued, -1)
+		atomic.AddInt64(&client.stats.Dropped, 1)
+	default:
+	}
+
+	select {
+	case client.outbound <- line:
+		atomic.AddInt64(&client.stats.Queued, 1)
+		return true
+	default: // Otra respuesta ocupo el espacio, descartamos esta
+		atomic.AddInt64(&client.stats.Dropped, 1)
+		return false
+	}
+}
+
+/* Funcion
+ * Nombre: responseWriteHandle
+ * Descripcion: Funcion encargada de escribir a la conexion las respuestas de la cola de salida del cliente,
+ * termina cuando termina la conexion o cuando falla una escritura */
+func (client *Client) responseWriteHandle() {
+
+	for {
+		select {
+
+		case <-client.done: // La conexion termino
+			return
+
+		case <-client.closing: // Se solicito cerrar la conexion, escribimos antes lo que quedo en la cola
+			client.flush()
+			client.connection.Close()
+			return
+
+		case line := <-client.outbound:
+			atomic.AddInt64(&client.stats.Queued, -1)
+			client.setWriteDeadline()
+
+			if !client.write(line) { // Si falla la escritura cerramos la conexion
+				client.connection.Close()
+				return
+			}
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: write
+ * Descripcion: Escribe una linea a la conexion del cliente
+ * @line: linea a escribir
+ * return: @bool: true si se escribio completa */
+func (client *Client) write(line []byte) bool {
+
+	n, err := client.connection.Write(line)
+	atomic.AddInt64(&client.metrics.bytesOut, int64(n))
+
+	if err != nil {
+		client.log().Warn("write failed", "error", err)
+		return false
+	}
+	return true
+}
+
+/* Funcion
+ * Nombre: flush
+ * Descripcion: Escribe las respuestas que quedan en la cola de salida antes de cerrar la conexion, todas dentro de
+ * un mismo tiempo maximo para que un cliente que no lee no retrase el cierre */
+func (client *Client) flush() {
+
+	timeout := client.config.WriteTimeout
+
+	if timeout <= 0 {
+		timeout = closeTimeout
+	}
+
+	if err := client.connection.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		client.log().Warn("set write deadline failed", "error", err)
+	}
+
+	for {
+		select {
+		case line := <-client.outbound:
+			atomic.AddInt64(&client.stats.Queued, -1)
+
+			if !client.write(line) {
+				return
+			}
+		default: // La cola quedo vacia
+			return
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: drain
+ * Descripcion: Descarta las respuestas que quedaron en la cola de salida de un cliente desconectado */
+func (client *Client) drain() {
+
+	for {
+		select {
+		case <-client.outbound:
+			atomic.AddInt64(&client.stats.Queued, -1)
+		default:
+			return
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: disconnect
+ * Descripcion: Informa al cliente un error y cierra su conexion despues de escribir las respuestas pendientes.
+ * El cierre no pasa por la cola de salida, asi una cola llena no puede descartarlo
+ * @e: razon de la desconexion */
+func (client *Client) disconnect(e error) {
+
+	client.log().Info("disconnecting client", "reason", e)
+	client.writeError(e)
+
+	client.closeOnce.Do(func() { close(client.closing) })
+}
+
+/* Funcion
+ * Nombre: QueueDepth
+ * Descripcion: Retorna la cantidad de respuestas en la cola de salida del cliente */
+func (client *Client) QueueDepth() int {
+
+	return len(client.outbound)
+}
+
+/* Funcion
+ * Nombre: QueueStats
+ * Descripcion: Retorna las metricas de las colas de salida de todos los clientes del servidor */
+func (server *Server) QueueStats() QueueStats {
+
+	return QueueStats{
+		Queued:        atomic.LoadInt64(&server.queueStats.Queued),
+		Dropped:       atomic.LoadInt64(&server.queueStats.Dropped),
+		SlowConsumers: atomic.LoadInt64(&server.queueStats.SlowConsumers),
+	}
+}
@@ -0,0 +1,297 @@
+package models
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Error informado al cliente cuando excede un limite
+var errRateLimited = errors.New("rate limit exceeded, slow down")
+
+// Intervalo con el que el servidor descarta el estado de las IPs inactivas
+const limiterSweepInterval = time.Minute
+
+// Estructura para un balde de fichas (token bucket)
+type bucket struct {
+	rate   float64   // Fichas que se recuperan por segundo, cero para no limitar
+	burst  float64   // Fichas maximas del balde
+	tokens float64   // Fichas disponibles
+	last   time.Time // Ultima vez que se recuperaron fichas
+}
+
+/* Funcion
+ * Nombre: newBucket
+ * Descripcion: Funcion encargada de crear un balde lleno apartir de la estructura */
+func newBucket(rate float64, burst int) *bucket {
+
+	if burst < 1 { // El balde debe permitir al menos una ficha
+		burst = 1
+	}
+
+	return &bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+/* Funcion
+ * Nombre: allow
+ * Descripcion: Toma fichas del balde si estan disponibles
+ * @n: cantidad de fichas a tomar
+ * @now: momento actual
+ * return: @bool: true si habia fichas suficientes */
+func (b *bucket) allow(n float64, now time.Time) bool {
+
+	if b.rate <= 0 { // Limite deshabilitado
+		return true
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate // Recuperamos las fichas del tiempo transcurrido
+	b.last = now
+
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	if n > b.burst { // Una solicitud mas grande que el balde se limita al balde completo
+		n = b.burst
+	}
+
+	if b.tokens < n {
+		return false
+	}
+
+	b.tokens -= n
+	return true
+}
+
+/* Funcion
+ * Nombre: full
+ * Descripcion: Indica si el balde ya recupero todas sus fichas, entonces equivale a un balde nuevo
+ * @now: momento actual */
+func (b *bucket) full(now time.Time) bool {
+
+	return b.rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// Estructura para los limites de un cliente o una IP
+type limits struct {
+	commands *bucket // Comandos
+	messages *bucket // Mensajes (MSG, DM)
+	bytes    *bucket // Bytes recibidos
+}
+
+/* Funcion
+ * Nombre: allow
+ * Descripcion: Consume de los limites una solicitud
+ * @size: tamaño de la solicitud en bytes
+ * @message: true si la solicitud es un mensaje
+ * @now: momento actual
+ * return: @bool: true si la solicitud no excede ningun limite */
+func (l *limits) allow(size int, message bool, now time.Time) bool {
+
+	if !l.bytes.allow(float64(size), now) || !l.commands.allow(1, now) {
+		return false
+	}
+	return !message || l.messages.allow(1, now)
+}
+
+/* Funcion
+ * Nombre: full
+ * Descripcion: Indica si todos los baldes de los limites estan llenos
+ * @now: momento actual */
+func (l *limits) full(now time.Time) bool {
+
+	return l.commands.full(now) && l.messages.full(now) && l.bytes.full(now)
+}
+
+// Estructura para el estado de una IP en el limitador
+type ipState struct {
+	limits      limits    // Limites compartidos por todas las conexiones de la IP
+	strikes     int       // Excesos del limite dentro de la ventana actual
+	lastStrike  time.Time // Fecha del ultimo exceso
+	bannedUntil time.Time // Fecha hasta la que la IP esta baneada
+}
+
+// Estructura para el limitador de solicitudes del servidor, compartido por las conexiones de todos los clientes
+type limiter struct {
+	mu     sync.Mutex
+	config *Config             // Configuracion de los limites del servidor
+	ips    map[string]*ipState // Estado de cada IP
+}
+
+/* Funcion
+ * Nombre: newLimiter
+ * Descripcion: Funcion encargada de crear el limitador apartir de la estructura */
+func newLimiter(config *Config) *limiter {
+
+	return &limiter{
+		config: config,
+		ips:    make(map[string]*ipState),
+	}
+}
+
+/* Funcion
+ * Nombre: newClientLimits
+ * Descripcion: Crea los limites propios de un cliente segun la configuracion */
+func (l *limiter) newClientLimits() limits {
+
+	return limits{
+		commands: newBucket(l.config.CommandRate, l.config.CommandBurst),
+		messages: newBucket(l.config.MessageRate, l.config.MessageBurst),
+		bytes:    newBucket(l.config.ByteRate, l.config.ByteBurst),
+	}
+}
+
+/* Funcion
+ * Nombre: state
+ * Descripcion: Retorna el estado de una IP, creandolo si no existe. Debe llamarse con mu tomado */
+func (l *limiter) state(ip string) *ipState {
+
+	state, ok := l.ips[ip]
+
+	if !ok {
+		state = &ipState{
+			limits: limits{
+				commands: newBucket(l.config.IPCommandRate, l.config.IPCommandBurst),
+				messages: newBucket(l.config.IPMessageRate, l.config.IPMessageBurst),
+				bytes:    newBucket(l.config.IPByteRate, l.config.IPByteBurst),
+			},
+		}
+		l.ips[ip] = state
+	}
+	return state
+}
+
+/* Funcion
+ * Nombre: allowIP
+ * Descripcion: Consume de los limites de una IP una solicitud
+ * return: @bool: true si la solicitud no excede los limites de la IP */
+func (l *limiter) allowIP(ip string, size int, message bool, now time.Time) bool {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.state(ip).limits.allow(size, message, now)
+}
+
+/* Funcion
+ * Nombre: strike
+ * Descripcion: Registra un exceso del limite de una IP y la banea si acumula demasiados dentro de la ventana
+ * return: @bool: true si la IP quedo baneada */
+func (l *limiter) strike(ip string, now time.Time) bool {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.BanStrikes <= 0 { // Baneos deshabilitados
+		return false
+	}
+
+	state := l.state(ip)
+
+	if now.Sub(state.lastStrike) > l.config.BanWindow { // Si paso la ventana volvemos a contar
+		state.strikes = 0
+	}
+
+	state.strikes++
+	state.lastStrike = now
+
+	if state.strikes >= l.config.BanStrikes {
+		state.strikes = 0
+		state.bannedUntil = now.Add(l.config.BanDuration)
+		return true
+	}
+	return false
+}
+
+/* Funcion
+ * Nombre: banned
+ * Descripcion: Indica si una IP esta baneada temporalmente */
+func (l *limiter) banned(ip string, now time.Time) bool {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.ips[ip]
+	return ok && now.Before(state.bannedUntil)
+}
+
+/* Funcion
+ * Nombre: sweep
+ * Descripcion: Descarta el estado de las IPs que ya no tienen baneo, excesos dentro de la ventana ni fichas por
+ * recuperar. Ese estado equivale al que se crearia de nuevo, asi el mapa no crece con cada IP que se conecto alguna vez
+ * @now: momento actual
+ * return: @int: cantidad de IPs descartadas */
+func (l *limiter) sweep(now time.Time) int {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := 0
+
+	for ip, state := range l.ips {
+
+		if now.Before(state.bannedUntil) { // Conservamos los baneos vigentes
+			continue
+		}
+
+		if state.strikes > 0 && now.Sub(state.lastStrike) <= l.config.BanWindow { // Y los excesos que aun cuentan
+			continue
+		}
+
+		if state.limits.full(now) {
+			delete(l.ips, ip)
+			removed++
+		}
+	}
+	return removed
+}
+
+/* Funcion
+ * Nombre: HostOf
+ * Descripcion: Retorna la IP de una direccion de red */
+func HostOf(address net.Addr) string {
+
+	host, _, err := net.SplitHostPort(address.String())
+
+	if err != nil { // Si la direccion no tiene puerto la usamos completa
+		return address.String()
+	}
+	return host
+}
+
+/* Funcion
+ * Nombre: ban
+ * Descripcion: Banea una IP hasta la fecha dada, si ya estaba baneada por mas tiempo se conserva el baneo mayor */
+func (l *limiter) ban(ip string, until time.Time) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if state := l.state(ip); until.After(state.bannedUntil) {
+		state.bannedUntil = until
+	}
+}
+
+/* Funcion
+ * Nombre: bans
+ * Descripcion: Retorna las IPs baneadas en la fecha dada */
+func (l *limiter) bans(now time.Time) []BanInfo {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bans := make([]BanInfo, 0)
+
+	for ip, state := range l.ips {
+		if now.Before(state.bannedUntil) {
+			bans = append(bans, BanInfo{Kind: BanIP, Target: ip, Until: state.bannedUntil})
+		}
+	}
+	return bans
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+/* Funcion
+ * Nombre: TestBucketRefill
+ * Descripcion: El balde recupera sus fichas segun el tiempo transcurrido, sin pasar de su capacidad */
+func TestBucketRefill(t *testing.T) {
+
+	start := time.Now()
+	b := newBucket(2, 3) // 2 fichas por segundo, hasta 3
+	b.last = start
+
+	steps := []struct {
+		after time.Duration // Tiempo desde el inicio
+		n     float64
+		allow bool
+	}{
+		{0, 1, true},
+		{0, 1, true},
+		{0, 1, true},
+		{0, 1, false},                         // Balde vacio
+		{250 * time.Millisecond, 1, false},    // Media ficha
+		{500 * time.Millisecond, 1, true},     // Una ficha
+		{time.Hour, 3, true},                  // Se llena sin pasar de 3
+		{time.Hour, 1, false},                 // Sin tiempo transcurrido no recupera fichas
+		{time.Hour + 2*time.Second, 10, true}, // Una solicitud mas grande que el balde toma el balde completo
+		{time.Hour + 2*time.Second, 1, false},
+	}
+
+	for i, step := range steps {
+		if got := b.allow(step.n, start.Add(step.after)); got != step.allow {
+			t.Fatalf("step %d: allow(%v) after %v = %v, want %v", i, step.n, step.after, got, step.allow)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestBucketDisabled
+ * Descripcion: Un balde sin tasa no limita y siempre esta lleno */
+func TestBucketDisabled(t *testing.T) {
+
+	b := newBucket(0, 1)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		if !b.allow(1000, now) {
+			t.Fatalf("allow %d on a disabled bucket = false", i)
+		}
+	}
+
+	if !b.full(now) {
+		t.Fatal("disabled bucket is not full")
+	}
+}
+
+/* Funcion
+ * Nombre: TestBucketFull
+ * Descripcion: El balde esta lleno cuando recupero todas las fichas que se tomaron */
+func TestBucketFull(t *testing.T) {
+
+	start := time.Now()
+	b := newBucket(1, 2)
+	b.last = start
+
+	if !b.full(start) {
+		t.Fatal("new bucket is not full")
+	}
+
+	b.allow(2, start)
+
+	if b.full(start.Add(time.Second)) {
+		t.Fatal("bucket full after recovering 1 of 2 tokens")
+	}
+	if !b.full(start.Add(2 * time.Second)) {
+		t.Fatal("bucket not full after recovering 2 of 2 tokens")
+	}
+}
+
+/* Funcion
+ * Nombre: TestLimitsMessages
+ * Descripcion: Solo las solicitudes que son mensajes consumen el balde de mensajes */
+func TestLimitsMessages(t *testing.T) {
+
+	l := limits{commands: newBucket(10, 10), messages: newBucket(1, 1), bytes: newBucket(1000, 1000)}
+	now := time.Now()
+
+	if !l.allow(10, true, now) {
+		t.Fatal("first message rejected")
+	}
+	if l.allow(10, true, now) {
+		t.Fatal("second message allowed with an empty message bucket")
+	}
+	if !l.allow(10, false, now) {
+		t.Fatal("command rejected by the message bucket")
+	}
+	if l.allow(2000, false, now) {
+		t.Fatal("request larger than the byte bucket allowed")
+	}
+}
+
+/* Funcion
+ * Nombre: TestLimiterStrikes
+ * Descripcion: Una IP se banea al acumular BanStrikes excesos dentro de la ventana */
+func TestLimiterStrikes(t *testing.T) {
+
+	now := time.Now()
+	l := newLimiter(&Config{BanStrikes: 3, BanWindow: time.Minute, BanDuration: time.Hour})
+
+	if l.strike("10.0.0.1", now) || l.strike("10.0.0.1", now) {
+		t.Fatal("ip banned before 3 strikes")
+	}
+	if l.strike("10.0.0.1", now.Add(2*time.Minute)) { // Fuera de la ventana vuelve a contar
+		t.Fatal("ip banned counting strikes outside the window")
+	}
+	l.strike("10.0.0.1", now.Add(2*time.Minute))
+
+	if !l.strike("10.0.0.1", now.Add(2*time.Minute)) {
+		t.Fatal("ip not banned after 3 strikes within the window")
+	}
+	if !l.banned("10.0.0.1", now.Add(time.Hour)) || l.banned("10.0.0.1", now.Add(3*time.Hour)) {
+		t.Fatal("ban does not last BanDuration")
+	}
+}
+
+/* Funcion
+ * Nombre: TestLimiterSweep
+ * Descripcion: El barrido descarta las IPs inactivas y conserva las baneadas, con excesos recientes o con fichas por recuperar */
+func TestLimiterSweep(t *testing.T) {
+
+	now := time.Now()
+	l := newLimiter(&Config{IPCommandRate: 1, IPCommandBurst: 10, BanStrikes: 5, BanWindow: time.Minute})
+
+	l.allowIP("10.0.0.1", 1, false, now)                  // Inactiva, recupera su ficha en 1s
+	l.allowIP("10.0.0.2", 1, false, now.Add(time.Minute)) // Tomo una ficha justo antes del barrido
+	l.strike("10.0.0.3", now.Add(time.Minute))            // Exceso dentro de la ventana
+	l.ban("10.0.0.4", now.Add(time.Hour))                 // Baneada
+	l.ban("10.0.0.5", now)                                // Baneo vencido
+
+	if removed := l.sweep(now.Add(time.Minute)); removed != 2 {
+		t.Fatalf("sweep removed %d ips, want 2", removed)
+	}
+
+	for _, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		if _, ok := l.ips[ip]; !ok {
+			t.Errorf("sweep removed %s", ip)
+		}
+	}
+
+	if removed := l.sweep(now.Add(2 * time.Hour)); removed != 3 {
+		t.Fatalf("second sweep removed %d ips, want 3", removed)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tamaño maximo de una reaccion, alcanza para los emoji compuestos
+const maxReactionSize = 32
+
+// Reacciones distintas que puede tener un mensaje
+const maxReactions = 20
+
+// Estructura para una reaccion de un mensaje con los clientes que la usaron, en orden
+type reaction struct {
+	token string   // Emoji o texto corto de la reaccion
+	users []string // Nombres de los clientes que reaccionaron
+}
+
+// Estructura con los datos publicos de una reaccion
+type ReactionInfo struct {
+	Reaction string   `json:"reaction"`
+	Count    int      `json:"count"`
+	Users    []string `json:"users"`
+}
+
+/* Funcion
+ * Nombre: parseReaction
+ * Descripcion: Valida una reaccion enviada como argumento: un emoji o un texto corto, sin espacios ni los
+ * separadores de los listados del protocolo
+ * @arg: argumento del cliente
+ * return: @string: reaccion
+ *         @error: err si no es una reaccion valida */
+func parseReaction(arg []byte) (string, error) {
+
+	token := string(arg)
+
+	invalid := len(token) > maxReactionSize || !utf8.ValidString(token) || strings.ContainsAny(token, ",;:")
+
+	for _, r := range token {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			invalid = true
+		}
+	}
+
+	if invalid {
+		return "", errors.New("invalid reaction " + strconv.Quote(token) + ", use an emoji or a word of up to " + strconv.Itoa(maxReactionSize) + " bytes")
+	}
+	return token, nil
+}
+
+/* Funcion
+ * Nombre: react
+ * Descripcion: Agrega la reaccion de un cliente al mensaje, las reacciones conservan el orden en que se usaron
+ * @token: reaccion
+ * @user: nombre del cliente
+ * return: @error: err si el cliente ya reacciono asi o el mensaje tiene demasiadas reacciones */
+func (message *Message) react(token string, user string) error {
+
+	for _, r := range message.reactions {
+		if r.token == token {
+			for _, name := range r.users {
+				if name == user {
+					return errors.New("already reacted with " + token + " to message " + strconv.FormatUint(message.id, 10))
+				}
+			}
+			r.users = append(r.users, user)
+			return nil
+		}
+	}
+
+	if len(message.reactions) >= maxReactions {
+		return errors.New("message " + strconv.FormatUint(message.id, 10) + " has too many reactions")
+	}
+
+	message.reactions = append(message.reactions, &reaction{token: token, users: []string{user}})
+	return nil
+}
+
+/* Funcion
+ * Nombre: unreact
+ * Descripcion: Quita la reaccion de un cliente al mensaje, la reaccion desaparece cuando nadie la usa
+ * @token: reaccion
+ * @user: nombre del cliente
+ * return: @error: err si el cliente no habia reaccionado asi */
+func (message *Message) unreact(token string, user string) error {
+
+	for i, r := range message.reactions {
+		if r.token != token {
+			continue
+		}
+
+		for j, name := range r.users {
+			if name == user {
+				r.users = append(r.users[:j], r.users[j+1:]...)
+				if len(r.users) == 0 {
+					message.reactions = append(message.reactions[:i], message.reactions[i+1:]...)
+				}
+				return nil
+			}
+		}
+	}
+	return errors.New("no reaction " + token + " to message " + strconv.FormatUint(message.id, 10))
+}
+
+/* Funcion
+ * Nombre: reactionCounts
+ * Descripcion: Retorna las reacciones del mensaje en el formato del protocolo: reaccion:cantidad separadas por
+ * espacios, vacio si no tiene */
+func (message *Message) reactionCounts() string {
+
+	counts := make([]string, len(message.reactions))
+
+	for i, r := range message.reactions {
+		counts[i] = r.token + ":" + strconv.Itoa(len(r.users))
+	}
+	return strings.Join(counts, " ")
+}
+
+/* Funcion
+ * Nombre: reactionInfo
+ * Descripcion: Retorna los datos publicos de las reacciones del mensaje, nil si no tiene */
+func (message *Message) reactionInfo() []ReactionInfo {
+
+	if len(message.reactions) == 0 {
+		return nil
+	}
+
+	info := make([]ReactionInfo, len(message.reactions))
+
+	for i, r := range message.reactions {
+		info[i] = ReactionInfo{Reaction: r.token, Count: len(r.users), Users: append([]string(nil), r.users...)}
+	}
+	return info
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/* Funcion
+ * Nombre: TestReactLimit
+ * Descripcion: Un mensaje admite hasta maxReactions reacciones distintas, y las existentes se pueden seguir usando */
+func TestReactLimit(t *testing.T) {
+
+	message := NewMessage(nil, "ana", []byte("hola"), nil)
+	message.id = 1
+
+	for i := 0; i < maxReactions; i++ {
+		if err := message.react("r"+strconv.Itoa(i), "ana"); err != nil {
+			t.Fatalf("reaction %d: %v", i+1, err)
+		}
+	}
+
+	err := message.react("extra", "ana")
+	if err == nil || err.Error() != "message 1 has too many reactions" {
+		t.Fatalf("reaction %d = %v, want too many reactions", maxReactions+1, err)
+	}
+
+	if err := message.react("r0", "bob"); err != nil { // Sumarse a una reaccion existente no agrega otra
+		t.Fatalf("reacting with an existing reaction at the limit = %v", err)
+	}
+	if err := message.react("r0", "bob"); err == nil {
+		t.Fatal("same user reacted twice with the same reaction")
+	}
+
+	if err := message.unreact("r5", "ana"); err != nil { // Al quitar una reaccion queda espacio para otra
+		t.Fatalf("unreact = %v", err)
+	}
+	if err := message.react("extra", "ana"); err != nil {
+		t.Fatalf("reaction after freeing a slot = %v", err)
+	}
+
+	counts := strings.Fields(message.reactionCounts())
+	if len(counts) != maxReactions || counts[0] != "r0:2" || counts[maxReactions-1] != "extra:1" {
+		t.Fatalf("reactionCounts = %q", message.reactionCounts())
+	}
+}
+
+/* Funcion
+ * Nombre: TestParseReaction
+ * Descripcion: Las reacciones son emoji o palabras cortas sin los separadores de los listados */
+func TestParseReaction(t *testing.T) {
+
+	tests := []struct {
+		reaction string
+		valid    bool
+	}{
+		{"👍", true},
+		{"👨‍👩‍👧", true}, // Emoji compuesto
+		{"ok", true},
+		{strings.Repeat("a", maxReactionSize), true},
+		{strings.Repeat("a", maxReactionSize+1), false},
+		{"a b", false},
+		{"a,b", false},
+		{"a;b", false},
+		{"a:b", false}, // Separa la reaccion de su cantidad en los listados
+		{"\x00", false},
+		{"\xff", false},
+	}
+
+	for _, test := range tests {
+		if _, err := parseReaction([]byte(test.reaction)); (err == nil) != test.valid {
+			t.Errorf("parseReaction(%q) = %v, want valid %v", test.reaction, err, test.valid)
+		}
+	}
+}
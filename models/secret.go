@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// Tamaño en bytes de la sal aleatoria de cada contraseña
+const saltSize = 16
+
+/* Funcion
+ * Nombre: hashSecret
+ * Descripcion: Retorna la sal aleatoria seguida del SHA-256 de la sal y la contraseña, para guardar las
+ * contraseñas de los nombres y los canales sin su texto plano
+ * @password: contraseña a guardar
+ * return: @[]byte: sal y hash de la contraseña
+ *         @error: err si no se pudo generar la sal */
+func hashSecret(password string) ([]byte, error) {
+
+	salt := make([]byte, saltSize)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(append(append([]byte(nil), salt...), password...))
+	return append(salt, sum[:]...), nil
+}
+
+/* Funcion
+ * Nombre: checkSecret
+ * Descripcion: Comprueba una contraseña contra el hash guardado por hashSecret, en tiempo constante
+ * @hash: sal y hash guardados
+ * @password: contraseña recibida
+ * return: @bool: true si la contraseña coincide */
+func checkSecret(hash []byte, password string) bool {
+
+	if len(hash) != saltSize+sha256.Size {
+		return false
+	}
+
+	sum := sha256.Sum256(append(append([]byte(nil), hash[:saltSize]...), password...))
+	return subtle.ConstantTimeCompare(hash[saltSize:], sum[:]) == 1
+}
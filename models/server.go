@@ -1,19 +1,43 @@
 package models
 
 import (
+	"errors"
 	"net"
+	"os"
 	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-//Estructura para la creacion del servidor
+// Estructura para la creacion del servidor
 type Server struct {
-	clients          map[net.Addr]*Client // Mapa de clientes en el servidor
-	channels         map[string]*Channel  // Canales del servidor
-	commands         chan Command         // Comando para ser analizado, es modificado por cada solicitud
-	ReqAndRes        string               // Solicitudes y respuestas de el servidor
+	mu               sync.RWMutex             // Protege los clientes y sus nombres, los modifica solo Run
+	clients          map[net.Addr]*Client     // Mapa de clientes en el servidor
+	nicknames        map[string]*Client       // Clientes conectados segun su nombre registrado
+	accounts         map[string][]byte        // Nombres reclamados con contraseña, con la sal y el hash de su contraseña
+	userBans         map[string]time.Time     // Nombres baneados por el administrador, hasta la fecha del baneo
+	shards           []*shard                 // Particiones de los canales del servidor
+	startShards      sync.Once                // Inicia una sola vez las rutinas de las particiones
+	directs          map[string]*Conversation // Conversaciones directas entre clientes
+	pending          map[string][]*Message    // Mensajes directos por entregar a clientes desconectados
+	commands         chan Command             // Comando para ser analizado, es modificado por cada solicitud
+	reqAndRes        string                   // Ultima solicitud y respuesta del servidor para la consola, se lee con TakeResponse
+	reqAndResMu      sync.Mutex               // Las particiones escriben reqAndRes desde distintas rutinas
 	ClientOnlineReq  chan *Client
 	clientOfflineReq chan *Client
-	ServerOn         bool // Define si el servidor esta apagado o encendido
+	ServerOn         bool          // Define si el servidor esta apagado o encendido
+	stopMu           sync.Mutex    // Protege stopped
+	stopped          chan struct{} // Se cierra en Stop, las rutinas de los clientes dejan de esperar a Run
+	Config           Config        // Configuracion de los limites del servidor
+	limiter          *limiter      // Limitador de solicitudes por IP, comparte la configuracion del servidor
+	queueStats       *QueueStats   // Metricas de las colas de salida de los clientes
+	metrics          *Metrics      // Metricas del servidor, se exponen con WriteMetrics
+	events           *events       // Suscriptores a los eventos del servidor
+	integrations     *integrations // Tokens de las integraciones que publican en los canales
+	Logger           *Logger       // Registro del servidor, los clientes y el servidor TCP derivan el suyo de este
+	started          time.Time     // Fecha de creacion del servidor
 }
 
 /* Funcion
@@ -21,28 +45,107 @@ type Server struct {
  * Descripcion: Funcion encargada de crear el servidor apartir de la estructura */
 func NewServer() *Server {
 
-	return &Server{
+	server := &Server{
 		clients:          make(map[net.Addr]*Client),
-		channels:         make(map[string]*Channel),
+		nicknames:        make(map[string]*Client),
+		accounts:         make(map[string][]byte),
+		userBans:         make(map[string]time.Time),
+		shards:           make([]*shard, ShardCount),
+		directs:          make(map[string]*Conversation),
+		pending:          make(map[string][]*Message),
 		commands:         make(chan Command),
-		ReqAndRes:        "",
+		reqAndRes:        "",
 		ClientOnlineReq:  make(chan *Client),
 		clientOfflineReq: make(chan *Client),
 		ServerOn:         false,
+		stopped:          make(chan struct{}),
+		started:          time.Now(),
 	}
+	server.limiter = newLimiter(&server.Config)
+	server.queueStats = &QueueStats{}
+	server.metrics = newMetrics()
+	server.events = &events{subscribers: make(map[chan Event]bool)}
+	server.integrations = &integrations{tokens: make(map[string]*Integration)}
+	server.Logger, _ = NewLogger(os.Stderr, "info", FormatLogfmt) // Registro por defecto, se reemplaza segun la configuracion
+
+	for i := range server.shards {
+		server.shards[i] = newShard()
+	}
+
+	return server
 }
 
 var ServerOn = true
 
+// Error informado al cliente cuando el servidor se apaga antes de procesar su solicitud
+var errServerStopped = errors.New("server stopped")
+
+/* Funcion
+ * Nombre: Stop
+ * Descripcion: Apaga el servidor: Run termina y las rutinas de los clientes que esperan al servidor dejan de
+ * esperarlo. El servidor se puede volver a encender con Run */
+func (server *Server) Stop() {
+
+	server.ServerOn = false
+
+	server.stopMu.Lock()
+	defer server.stopMu.Unlock()
+
+	close(server.stopped)
+	server.stopped = make(chan struct{}) // Para el siguiente Run
+}
+
+/* Funcion
+ * Nombre: done
+ * Descripcion: Retorna el canal que se cierra al apagar el servidor con Stop */
+func (server *Server) done() <-chan struct{} {
+
+	server.stopMu.Lock()
+	defer server.stopMu.Unlock()
+
+	return server.stopped
+}
+
 /* Funcion
  * Nombre: Run
- * Descripcion: Ejecutara el comando que es asignado por la solicitud del cliente */
+ * Descripcion: Ejecutara el comando que es asignado por la solicitud del cliente. Los comandos sobre un canal
+ * los ejecuta la particion del canal en runShard, Run ejecuta los comandos generales */
 func (server *Server) Run() {
 
-	for server.ServerOn { // Mientras el servidor este prendido
+	server.startShards.Do(func() { // Iniciamos las particiones de los canales
+		for _, shard := range server.shards {
+			go server.runShard(shard)
+		}
+	})
+
+	sweep := time.NewTicker(limiterSweepInterval) // Descarta el estado de las IPs inactivas del limitador
+	defer sweep.Stop()
+
+	var heartbeat <-chan time.Time // Sin intervalo configurado el servidor no envia PING
+
+	if server.Config.PingInterval > 0 {
+		ticker := time.NewTicker(server.Config.PingInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	stopped := server.done()
+
+	for { // Mientras el servidor este prendido, hasta que Stop lo apague
 
 		select {
 
+		case <-stopped: // Stop apago el servidor mientras esperabamos
+			return
+
+		case <-heartbeat: // Enviamos PING a los clientes y desconectamos a los que no responden
+			server.heartbeat()
+
+		case now := <-sweep.C:
+			if removed := server.limiter.sweep(now); removed > 0 {
+				server.Logger.Debug("limiter swept", "ips", removed)
+			}
+
 		case client := <-server.ClientOnlineReq: // Conectamos un cliente al servidor
 			server.setClientOnline(client)
 
@@ -53,31 +156,111 @@ func (server *Server) Run() {
 
 			switch cmd.id { // Comando disponibles en el protocolo
 
-			case JOIN: // Cliente ingresa a un canal
-				server.joinChannel(cmd.sender.address, cmd.channel)
-
-			case LEAVE: // Cliente sale de un canal
-				server.leaveChannel(cmd.sender.address, cmd.channel)
-
-			case MSG: // Envia un mensaje al servidor
-				server.sendMessage(cmd.sender.address, cmd.channel, cmd.content, cmd.file)
-
-			case CREATE: // Crea un canal nuevo
-				server.createChannel(cmd.sender.address, cmd.channel)
+			case REG: // Cliente registra su nombre
+				server.registerClient(cmd.sender.address, cmd.target, cmd.password)
 
 			case LIST_CHN: // Lista los canales existentes
 				server.listChannels(cmd.sender.address)
 
-			case LIST_MSG: // Lista los mensajes de un canal
-				server.listMessages(cmd.sender.address, cmd.channel)
+			case DM: // Envia un mensaje directo a un cliente
+				server.sendDirect(cmd.sender.address, cmd.target, cmd.content, cmd.file)
+
+			case LIST_DM: // Lista las conversaciones directas de un cliente
+				server.listDirects(cmd.sender.address, cmd.target)
 
-			case LIST_USR: // Lista los cliente conectados de un canal
-				server.listUsrChannel(cmd.sender.address, cmd.channel)
+			case probe: // Sonda de salud, done confirma que el servidor procesa comandos
 			}
+			cmd.done()
+			server.metrics.observe(cmd)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: runShard
+ * Descripcion: Ejecuta los comandos sobre los canales de una particion, uno a la vez y con la particion bloqueada */
+func (server *Server) runShard(shard *shard) {
+
+	for cmd := range shard.commands {
+
+		shard.mu.Lock()
+
+		switch cmd.id { // Comandos sobre un canal
+
+		case JOIN: // Cliente ingresa a un canal
+			server.joinChannel(cmd.sender.address, cmd.channel, cmd.password)
+
+		case LEAVE: // Cliente sale de un canal
+			server.leaveChannel(cmd.sender.address, cmd.channel)
+
+		case MSG: // Envia un mensaje al servidor
+			server.sendMessage(cmd.sender.address, cmd.channel, cmd.content, cmd.file, cmd.message)
+
+		case CREATE: // Crea un canal nuevo
+			server.createChannel(cmd.sender.address, cmd.channel)
+
+		case LIST_MSG: // Lista los mensajes de un canal
+			server.listMessages(cmd.sender.address, cmd.channel)
+
+		case LIST_USR: // Lista los cliente conectados de un canal
+			server.listUsrChannel(cmd.sender.address, cmd.channel)
+
+		case INVITE: // Invita a un cliente a un canal
+			server.inviteClient(cmd.sender.address, cmd.channel, cmd.target)
+
+		case MODE: // Cambia los modos de un canal
+			server.setMode(cmd.sender.address, cmd.channel, cmd.mode, cmd.password)
+
+		case TOPIC: // Consulta o cambia el tema de un canal
+			server.topicChannel(cmd.sender.address, cmd.channel, cmd.content, cmd.desc)
+
+		case EDIT: // Edita un mensaje de un canal
+			server.editMessage(cmd.sender.address, cmd.channel, cmd.message, cmd.content)
+
+		case DELETE: // Elimina un mensaje de un canal
+			server.deleteMessage(cmd.sender.address, cmd.channel, cmd.message)
+
+		case THREAD: // Lista las respuestas de un mensaje
+			server.listThread(cmd.sender.address, cmd.channel, cmd.message)
+
+		case REACT, UNREACT: // Agrega o quita una reaccion a un mensaje
+			server.reactMessage(cmd.sender.address, cmd.channel, cmd.message, cmd.reaction, cmd.id == REACT)
+
+		case probe: // Sonda de salud, done confirma que la particion procesa comandos
 		}
+		cmd.done()
+		server.metrics.observe(cmd)
+
+		shard.mu.Unlock()
 	}
 }
 
+/* Funcion: heartbeat
+ * Envia un PING a cada cliente conectado y cierra la conexion de los clientes que no han respondido
+ * a los ultimos PingMisses PING. Al cerrarse la conexion el cliente solicita su desconexion del servidor */
+func (server *Server) heartbeat() {
+
+	now := time.Now()
+	deadline := server.Config.PingInterval * time.Duration(server.Config.PingMisses)
+
+	for _, client := range server.clients {
+
+		if server.Config.PingMisses > 0 && now.Sub(client.seen()) > deadline { // El cliente no responde, cerramos su conexion
+			client.disconnect(errors.New("ping timeout"))
+			continue
+		}
+		client.WriteResponse("PING " + strconv.FormatInt(now.UnixNano(), 10))
+	}
+}
+
+/* Funcion: Banned
+ * Indica si la IP de una direccion esta baneada temporalmente por abuso
+ * @param address direccion de la conexion */
+func (server *Server) Banned(address net.Addr) bool {
+
+	return server.limiter.banned(HostOf(address), time.Now())
+}
+
 /* Funcion: setClientOffline
  * Desconecta a un cliente del servidor
  * @param client cliente a desconectar */
@@ -85,10 +268,37 @@ func (server *Server) setClientOffline(c *Client) {
 
 	if _, exists := server.clients[c.address]; exists { // Manejamos que el cliente que solicita desconectarse exista en el servidor
 
+		server.mu.Lock()
 		delete(server.clients, c.address) // Lo eliminamos de los clientes
 
-		for _, channel := range server.channels { //Lo eliminamos de los canales
-			delete(channel.clients, c)
+		if c.nickname != "" && server.nicknames[c.nickname] == c { // Liberamos su nombre registrado
+			delete(server.nicknames, c.nickname)
+		}
+		server.mu.Unlock()
+
+		c.drain() // Descartamos las respuestas que no alcanzo a recibir
+		c.log().Info("client disconnected")
+		server.clientEvent(EventClientDisconnected, c)
+
+		// Lo eliminamos de los canales. Los comandos del cliente que las particiones procesen despues
+		// ya no lo encuentran en el servidor, asi que no puede volver a quedar en un canal
+		for _, shard := range server.shards {
+
+			shard.mu.Lock()
+
+			for _, channel := range shard.channels {
+
+				if channel.clients[c] {
+					delete(channel.clients, c)
+					channel.touch()
+					channel.broadcast("LEAVE " + channel.name + " " + c.name()) // Notificamos a los miembros restantes
+					server.channelEvent(EventChannelUpdated, channel)
+					server.memberEvent(EventMemberLeft, channel, c)
+				}
+				delete(channel.operators, c)
+			}
+
+			shard.mu.Unlock()
 		}
 	}
 }
@@ -101,23 +311,202 @@ func (server *Server) setClientOnline(client *Client) {
 	if _, exists := server.clients[client.address]; exists {
 		// Aqui se validara si el nombre de cliente esta disponible
 	} else {
+		server.mu.Lock()
 		server.clients[client.address] = client // Conectamos el cliente al servidor si el cliente no existe
+		server.mu.Unlock()
+		client.log().Info("client connected")
+		server.clientEvent(EventClientConnected, client)
+		atomic.AddInt64(&server.metrics.connections, 1)
+		close(client.online)
+	}
+}
+
+/* Funcion: client
+ * Busca un cliente conectado al servidor por su direccion, puede llamarse desde cualquier rutina
+ * @param address direccion del cliente
+ * return: el cliente y true si esta conectado */
+func (server *Server) client(address net.Addr) (*Client, bool) {
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	client, ok := server.clients[address]
+	return client, ok
+}
+
+/* Funcion: findClient
+ * Busca un cliente conectado al servidor por su nombre
+ * @param name nombre del cliente buscado
+ * return: el cliente, nil si no esta conectado */
+func (server *Server) findClient(name string) *Client {
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	if client, ok := server.nicknames[name]; ok { // Buscamos primero entre los nombres registrados
+		return client
+	}
+
+	for _, client := range server.clients { // Si no, por la direccion del cliente
+		if client.name() == name {
+			return client
+		}
 	}
+	return nil
+}
+
+/* Funcion: registerClient
+ * Registra el nombre de un cliente. El primer registro con contraseña reclama el nombre, y desde entonces
+ * registrarlo exige esa contraseña. Solo un nombre verificado con su contraseña recibe los mensajes directos pendientes
+ * @param sender direccion del emisor de la solicitud.
+ * @param nickname nombre a registrar
+ * @param password contraseña del nombre, vacia para registrarse como invitado */
+func (server *Server) registerClient(sender net.Addr, nickname string, password string) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		if server.userBanned(nickname) { // Manejamos que el nombre no este baneado por el administrador
+			client.respondError(errors.New("nickname " + nickname + " is banned"))
+			return
+		}
+
+		if owner, ok := server.nicknames[nickname]; ok && owner != client { // Manejamos que el nombre no este en uso por otro cliente conectado
+			client.respondError(errors.New("nickname " + nickname + " already in use"))
+			return
+		}
+
+		hash, verified := server.accounts[nickname]
+
+		if verified && !checkSecret(hash, password) { // Manejamos que el nombre reclamado se registre con su contraseña
+			client.respondError(errors.New("nickname " + nickname + " is registered, wrong password"))
+			return
+		}
+
+		if !verified && password != "" { // El primer registro con contraseña reclama el nombre
+
+			hash, err := hashSecret(password)
+
+			if err != nil {
+				client.log().Error("hash password failed", "error", err)
+				client.respondError(errors.New("internal error registering nickname"))
+				return
+			}
+			server.accounts[nickname] = hash
+			verified = true
+		}
+
+		server.mu.Lock()
+
+		if client.nickname != "" { // Si el cliente ya tenia un nombre lo liberamos
+			delete(server.nicknames, client.nickname)
+		}
+
+		client.setNickname(nickname, verified)
+		server.nicknames[nickname] = client
+
+		server.mu.Unlock()
+
+		client.respond("REG " + nickname)
+		client.log().Info("client registered")
+		server.clientEvent(EventClientRegistered, client)
+		server.WriteResponse("REG "+nickname, "CLIENT REGISTERED")
+
+		if verified { // Entregamos los mensajes directos recibidos mientras estaba desconectado
+			for _, message := range server.pending[nickname] {
+				client.WriteResponse("DM " + message.format())
+			}
+			delete(server.pending, nickname)
+		}
+	}
+}
+
+/* Funcion: channel
+ * Busca un canal en su particion, debe llamarse con la particion del canal bloqueada
+ * @param channelName nombre del canal buscado
+ * return: el canal y true si existe */
+func (server *Server) channel(channelName string) (*Channel, bool) {
+
+	channel, ok := shardFor(server.shards, channelName).channels[channelKey(channelName)]
+	return channel, ok
+}
+
+/* Funcion: lookupChannel
+ * Busca un canal del servidor e informa al cliente si no existe
+ * @param client cliente que solicita el canal
+ * @param channelName nombre del canal buscado
+ * return: el canal, nil si no existe */
+func (server *Server) lookupChannel(client *Client, channelName string) *Channel {
+
+	channel, ok := server.channel(channelName)
+
+	if !ok { // Manejamos que el canal exista
+		client.respondError(errors.New("no such channel " + channelName))
+		return nil
+	}
+	return channel
+}
+
+/* Funcion: accessChannel
+ * Busca un canal cuyos mensajes puede leer y escribir el cliente, e informa al cliente si no existe o
+ * si tiene modos y el cliente no es miembro
+ * @param client cliente que solicita el canal
+ * @param channelName nombre del canal buscado
+ * return: el canal, nil si no existe o el cliente no tiene acceso */
+func (server *Server) accessChannel(client *Client, channelName string) *Channel {
+
+	channel := server.lookupChannel(client, channelName)
+
+	if channel != nil && !channel.isAccessibleTo(client) { // Manejamos que el cliente pertenezca a un canal con modos
+		client.respondError(errors.New("not a member of channel " + channelName))
+		return nil
+	}
+	return channel
 }
 
 /* Funcion: joinChannel
  * Conecta a un cliente a un canal
  * @param sender direccion del emisor de la solicitud.
- * @param channelName nombre del canal a conectar */
-func (server *Server) joinChannel(sender net.Addr, channelName string) {
+ * @param channelName nombre del canal a conectar
+ * @param password contraseña enviada por el cliente, vacia si no la envio */
+func (server *Server) joinChannel(sender net.Addr, channelName string, password string) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel := server.lookupChannel(client, channelName)
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el cliente que solicita exista en el servidor
+		if channel == nil { // Manejamos que el canal a conectar exista
+			return
+		}
+
+		if channel.clients[client] { // Manejamos que el cliente no sea miembro del canal
+			client.respondError(errors.New("already a member of channel " + channelName))
+			return
+		}
 
-		if channel, ok := server.channels[channelName]; ok { // Manejamos que el canal a conectar exista
+		// Las invitaciones son para un nombre, solo las usa quien lo registro con su contraseña
+		nickname, verified := client.account()
+		invited := verified && channel.invites[nickname]
 
-			channel.clients[client] = true // Conectamos al cliente
-			server.WriteResponse("JOIN "+channelName, "CLIENT JOINED SUCCESSFULLY")
+		if channel.inviteOnly && !channel.operators[client] && !invited { // Manejamos que el cliente haya sido invitado
+			client.respondError(errors.New("channel " + channelName + " is invite only"))
+			return
 		}
+
+		if channel.password != nil && !channel.operators[client] && !checkSecret(channel.password, password) { // Manejamos que la contraseña sea correcta
+			client.respondError(errors.New("wrong password for channel " + channelName))
+			return
+		}
+
+		if invited {
+			delete(channel.invites, nickname) // La invitacion se consume al entrar
+		}
+		channel.clients[client] = true // Conectamos al cliente
+		channel.touch()
+
+		channel.broadcast("JOIN " + channel.name + " " + client.name()) // Notificamos a los miembros del canal, incluido el nuevo
+		server.channelEvent(EventChannelUpdated, channel)
+		server.memberEvent(EventMemberJoined, channel, client)
+		server.WriteResponse("JOIN "+channelName, "CLIENT JOINED SUCCESSFULLY")
 	}
 }
 
@@ -127,49 +516,289 @@ func (server *Server) joinChannel(sender net.Addr, channelName string) {
  * @param channelName nombre del canal a desconectar */
 func (server *Server) leaveChannel(sender net.Addr, channelName string) {
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el cliente que solicita exista en el servidor
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
 
-		if channel, ok := server.channels[channelName]; ok { // Manejamos que el canal a salir exista
+		channel := server.lookupChannel(client, channelName)
 
-			channel.clients[client] = true // Desconectamos al cliente
-			server.WriteResponse("LEAVE "+channelName, "CLIENT LEFT SUCCESSFULLY")
+		if channel == nil { // Manejamos que el canal a salir exista
+			return
 		}
+
+		if !channel.clients[client] { // Manejamos que el cliente sea miembro del canal
+			client.respondError(errors.New("not a member of channel " + channelName))
+			return
+		}
+
+		client.respond("LEAVE " + channel.name + " " + client.name()) // Confirmamos al cliente su salida
+		delete(channel.clients, client)                               // Desconectamos al cliente
+		channel.touch()
+
+		channel.broadcast("LEAVE " + channel.name + " " + client.name()) // Notificamos a los miembros restantes
+		server.channelEvent(EventChannelUpdated, channel)
+		server.memberEvent(EventMemberLeft, channel, client)
+		server.WriteResponse("LEAVE "+channelName, "CLIENT LEFT SUCCESSFULLY")
 	}
 }
 
 /* Funcion: sendMessage
- * Envia un mensaje al servidor
- * @param sender direccion del emisor de la solicitud.
+ * Envia un mensaje al servidor y lo notifica a los miembros del canal como MSG [nameChannel] [parentId] [entrada]
+ * @param sender direccion del emisor de la solicitud, de una integracion o del propio servidor
  * @param channelName nombre del canal destinatario del mensaje
- * @param message mensaje a enviar */
-func (server *Server) sendMessage(senderAddress net.Addr, channelName string, message []byte, file []byte) {
+ * @param message mensaje a enviar
+ * @param parent mensaje al que responde, cero si no es una respuesta
+ * return: el mensaje agregado al canal, nil si no se envio */
+func (server *Server) sendMessage(senderAddress net.Addr, channelName string, message []byte, file []byte, parent uint64) *Message {
+
+	if author, ok := server.author(senderAddress); ok { // Manejamos que el emisor exista en el servidor
+
+		channel, ok := server.channel(channelName)
+
+		if client, isClient := server.client(senderAddress); isClient { // Las integraciones y el servidor publican en cualquier canal
+			channel = server.accessChannel(client, channelName)
+			ok = channel != nil
+		}
+
+		if ok { // Manejamos que el canal destinatario exista y el emisor tenga acceso
+
+			var root *Message
+
+			if parent != 0 { // Manejamos que el mensaje al que responde exista
+				if root = channel.threadRoot(parent); root == nil {
+					if client, ok := server.client(senderAddress); ok {
+						client.respondError(errors.New("no such message " + strconv.FormatUint(parent, 10) + " in channel " + channelName))
+					}
+					return nil
+				}
+			}
+
+			msg := NewMessage(senderAddress, author, message, file)
 
-	if _, ok := server.clients[senderAddress]; ok { // Manejamos que el cliente que solicita exista en el servidor
+			if client, ok := server.client(senderAddress); ok { // El autor conserva sus mensajes en otra conexion solo si demostro ser el dueño del nombre
+				_, msg.verified = client.account()
+			}
+			channel.addMessage(msg)
 
-		if channel, ok := server.channels[channelName]; ok { // Manejamos que el canal destinatario exista
+			if root != nil {
+				server.addReply(channel, root, msg)
+			}
 
-			channel.messages[string(message)] = NewMessage(senderAddress, message, file)
+			// Notificamos a los miembros del canal, incluido el emisor: MSG [nameChannel] [parentId] [entrada]
+			channel.broadcast("MSG " + channel.name + " " + strconv.FormatUint(msg.parent, 10) + " " + msg.entry())
+			server.messageEvent(EventMessage, channel, msg)
+			server.metrics.message(channel.name)
 			server.WriteResponse("MSG "+senderAddress.String()+" "+channelName+" "+string(message)+" "+string(file), "MESSAGE RECEIVED")
+			return msg
+		}
+	}
+	return nil
+}
+
+/* Funcion: addReply
+ * Agrega una respuesta al hilo de un mensaje y la notifica como REPLY [nameChannel] [id_raiz] [entrada] a los
+ * participantes del hilo que son miembros del canal: el autor del mensaje raiz y los autores de sus respuestas
+ * @param channel canal del hilo
+ * @param root mensaje raiz del hilo
+ * @param reply respuesta agregada */
+func (server *Server) addReply(channel *Channel, root *Message, reply *Message) {
+
+	reply.parent = root.id
+	root.replies = append(root.replies, reply)
+
+	participants := map[string]bool{root.author: true}
+
+	for _, previous := range root.replies {
+		participants[previous.author] = true
+	}
+	delete(participants, reply.author) // Quien responde no se notifica a si mismo
+
+	notice := "REPLY " + channel.name + " " + strconv.FormatUint(root.id, 10) + " " + reply.entry()
+
+	for client := range channel.clients {
+		if participants[client.name()] {
+			client.WriteResponse(notice)
+		}
+	}
+}
+
+/* Funcion: author
+ * Busca el nombre con el que publica un emisor: el de un cliente conectado, una integracion o el propio servidor
+ * @param address direccion del emisor
+ * return: el nombre y true si el emisor existe */
+func (server *Server) author(address net.Addr) (string, bool) {
+
+	switch address := address.(type) {
+	case integrationAddr: // Las integraciones no tienen conexion, su token ya se valido
+		return address.name, true
+	case systemAddr: // Mensajes del propio servidor, el nombre ya usa un prefijo reservado
+		return address.name, true
+	}
+
+	if client, ok := server.client(address); ok {
+		return client.name(), true
+	}
+	return "", false
+}
+
+/* Funcion: editMessage
+ * Cambia el contenido de un mensaje de un canal, solo su autor o un operador del canal pueden editarlo
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal del mensaje
+ * @param id identificador del mensaje
+ * @param content contenido nuevo del mensaje */
+func (server *Server) editMessage(sender net.Addr, channelName string, id uint64, content []byte) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel, message := server.lookupMessage(client, channelName, id)
+
+		if message == nil || !server.canModify(client, channel, message) { // Manejamos que el mensaje exista y el cliente pueda modificarlo
+			return
 		}
+
+		message.edit(content)
+		channel.touch()
+
+		channel.broadcast("EDIT " + channel.name + " " + strconv.FormatUint(id, 10) + " " + escapeField(string(content))) // Notificamos a los miembros del canal, con el contenido escapado como en MSG
+		server.messageEvent(EventMessageEdited, channel, message)
+		server.WriteResponse("EDIT "+channelName+" "+strconv.FormatUint(id, 10), "MESSAGE EDITED")
 	}
 }
 
+/* Funcion: deleteMessage
+ * Elimina un mensaje de un canal dejando su marca en el historial, solo su autor o un operador del canal pueden eliminarlo
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal del mensaje
+ * @param id identificador del mensaje */
+func (server *Server) deleteMessage(sender net.Addr, channelName string, id uint64) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel, message := server.lookupMessage(client, channelName, id)
+
+		if message == nil || !server.canModify(client, channel, message) { // Manejamos que el mensaje exista y el cliente pueda modificarlo
+			return
+		}
+
+		server.removeMessage(channel, message)
+		server.WriteResponse("DELETE "+channelName+" "+strconv.FormatUint(id, 10), "MESSAGE DELETED")
+	}
+}
+
+/* Funcion: reactMessage
+ * Agrega o quita la reaccion de un cliente a un mensaje de un canal, solo los miembros del canal pueden reaccionar
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal del mensaje
+ * @param id identificador del mensaje
+ * @param reaction emoji o texto corto de la reaccion
+ * @param add true para agregar la reaccion, false para quitarla */
+func (server *Server) reactMessage(sender net.Addr, channelName string, id uint64, reaction string, add bool) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel, message := server.lookupMessage(client, channelName, id)
+
+		if message == nil { // Manejamos que el mensaje exista
+			return
+		}
+
+		if !channel.clients[client] && !channel.operators[client] { // Manejamos que el cliente pertenezca al canal
+			client.respondError(errors.New("not a member of channel " + channelName))
+			return
+		}
+
+		var err error
+		name, kind := client.name(), "REACT"
+
+		if add {
+			err = message.react(reaction, name)
+		} else {
+			err, kind = message.unreact(reaction, name), "UNREACT"
+		}
+
+		if err != nil {
+			client.respondError(err)
+			return
+		}
+
+		channel.broadcast(kind + " " + channel.name + " " + strconv.FormatUint(id, 10) + " " + reaction + " " + name) // Notificamos a los miembros del canal
+		server.messageEvent(EventMessageReacted, channel, message)
+		server.WriteResponse(kind+" "+channelName+" "+strconv.FormatUint(id, 10)+" "+reaction, "MESSAGE REACTIONS "+message.reactionCounts())
+	}
+}
+
+/* Funcion: lookupMessage
+ * Busca un mensaje de un canal e informa al cliente si no existe
+ * @param client cliente que solicita el mensaje
+ * @param channelName nombre del canal del mensaje
+ * @param id identificador del mensaje
+ * return: el canal y el mensaje, mensaje nil si no existe o ya se elimino */
+func (server *Server) lookupMessage(client *Client, channelName string, id uint64) (*Channel, *Message) {
+
+	channel := server.lookupChannel(client, channelName)
+
+	if channel == nil { // Manejamos que el canal exista
+		return nil, nil
+	}
+
+	message := channel.findMessage(id)
+
+	if message == nil || message.isDeleted() { // Manejamos que el mensaje exista
+		client.respondError(errors.New("no such message " + strconv.FormatUint(id, 10) + " in channel " + channelName))
+		return channel, nil
+	}
+	return channel, message
+}
+
+/* Funcion: canModify
+ * Indica si el cliente puede editar o eliminar un mensaje, e informa al cliente si no puede
+ * @param client cliente que solicita el cambio
+ * @param channel canal del mensaje
+ * @param message mensaje a modificar
+ * return: true si el cliente es el autor del mensaje o un operador del canal */
+func (server *Server) canModify(client *Client, channel *Channel, message *Message) bool {
+
+	if !channel.canModify(client, message) {
+		client.respondError(errors.New("not the author of message " + strconv.FormatUint(message.id, 10) + " nor an operator of channel " + channel.name))
+		return false
+	}
+	return true
+}
+
+/* Funcion: removeMessage
+ * Deja un mensaje como marca de eliminado y lo notifica a los miembros del canal, debe llamarse con la particion
+ * del canal bloqueada
+ * @param channel canal del mensaje
+ * @param message mensaje a eliminar */
+func (server *Server) removeMessage(channel *Channel, message *Message) {
+
+	message.remove()
+	channel.touch()
+
+	channel.broadcast("DELETE " + channel.name + " " + strconv.FormatUint(message.id, 10)) // Notificamos a los miembros del canal
+	server.messageEvent(EventMessageDeleted, channel, message)
+}
+
 /* Funcion: createChannel
  * Crea un canal para el servidor
  * @param sender direccion del emisor de la solicitud.
  * @param channelName nombre del canal que se desea crear */
 func (server *Server) createChannel(sender net.Addr, channelName string) {
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el cliente que solicita exista en el servidor
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
 
-		if _, ok := server.channels[channelName]; ok { // Manejamos que el canal a crear no exista
+		if _, ok := server.channel(channelName); ok { // Manejamos que el canal a crear no exista, sin importar mayusculas ni su forma Unicode
 
-			client.WriteResponse("FALSE")
+			client.respond("FALSE")
 			server.WriteResponse("CREATE "+channelName, "CHANNEL ALREADY EXISTS")
 
 		} else {
 
-			server.channels[channelName] = NewChannel(channelName) // Creamos el canal en el servidor
+			channel := NewChannel(channelName, client.name()) // Creamos el canal
+			channel.operators[client] = true                  // El creador del canal es su operador
+
+			shardFor(server.shards, channelName).channels[channelKey(channelName)] = channel // Agregamos el canal a su particion
+			client.log().Info("channel created", "channel", channelName)
+			server.channelEvent(EventChannelCreated, channel)
 			server.WriteResponse("CREATE "+channelName, "CHANNEL CREATED")
 		}
 	}
@@ -180,31 +809,42 @@ func (server *Server) createChannel(sender net.Addr, channelName string) {
  * @param sender direccion del emisor de la solicitud */
 func (server *Server) listChannels(sender net.Addr) {
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el cliente que solicita exista en el servidor
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
 
-		if len(server.channels) > 0 { // Verificamos si existen canales antes de proceder
+		channels := make([]string, 0) // array de strings para ordenar los canales por su fecha de creacion
 
-			response := ""
+		for _, shard := range server.shards { // Recorremos los canales de cada particion
 
-			channels := make([]string, 0) // array de strings para ordenar los canales por su fecha de creacion
+			shard.mu.RLock()
 
-			for _, values := range server.channels {
+			for _, values := range shard.channels {
 
-				//llenamos el array de canales con: fecha_creacion,emisor,null
-				channels = append(channels, values.date.Format("2006-01-02:15:04:05")+","+values.name+","+"null")
+				if !values.isVisibleTo(client) { // Los canales privados solo se listan a sus miembros
+					continue
+				}
+
+				//llenamos el array de canales con: fecha_creacion,nombre,tema,descripcion,creador,miembros,ultima_actividad
+				channels = append(channels, values.format())
 			}
 
+			shard.mu.RUnlock()
+		}
+
+		if len(channels) > 0 { // Verificamos si existen canales antes de proceder
+
+			response := ""
+
 			sort.Strings(channels) //Ordenamos el array de mensajes
 
 			for _, key := range channels {
 
 				// Juntamos los canales del array ordenado en la respuesta dividido por ;
-				// fecha_creacion,emisor,null;fecha_creacion,emisor,null
+				// fecha_creacion,nombre,...;fecha_creacion,nombre,...
 				response = response + key + ";"
 			}
 
 			server.WriteResponse("LIST_CHN", response)
-			client.WriteResponse(response)
+			client.respond(response)
 
 		}
 	}
@@ -216,36 +856,68 @@ func (server *Server) listChannels(sender net.Addr) {
  * @param channelName nombre del canal que se listaran sus mensajes */
 func (server *Server) listMessages(sender net.Addr, channelName string) {
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el cliente que solicita exista en el servidor
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
 
-		if channel, ok := server.channels[channelName]; ok { // Manejamos que el canal solicitado exista
+		if channel := server.accessChannel(client, channelName); channel != nil { // Manejamos que el canal exista y el cliente tenga acceso
 
 			if len(channel.messages) > 0 { // Verificamos si el canal tiene mensajes antes de proceder
 
 				response := ""
 
-				messages := make([]string, 0) // array de strings para ordenar los mensajes por su fecha
+				for _, message := range channel.messages { // Los mensajes ya estan en orden de llegada
 
-				for _, values := range channel.messages {
+					if message.parent != 0 { // Las respuestas se listan en el hilo de su mensaje con THREAD
+						continue
+					}
 
-					//llenamos el array de mensajes con: fecha_mensaje,emisor,mensaje,file
-					messages = append(messages, values.date.Format("2006-01-02:15:04:05")+","+values.sender.String()+","+string(values.content)+","+string(values.file))
+					// Juntamos los mensajes en la respuesta dividido por ;
+					// id,respuestas,fecha_mensaje,emisor,mensaje;id,respuestas,fecha_mensaje,emisor,mensaje
+					response = response + message.entry() + ";"
 				}
 
-				sort.Strings(messages) //Ordenamos el array de mensajes
+				server.WriteResponse("LIST_MSG "+channelName, response)
+				client.respond(response)
 
-				for _, key := range messages {
+			}
+		}
+	}
+}
 
-					// Juntamos los mensajes del array ordenado en la respuesta dividido por ;
-					// fecha_mensaje,emisor,mensaje;fecha_mensaje,emisor,mensaje
-					response = response + key + ";"
-				}
+/* Funcion: listThread
+ * Lista el hilo de un mensaje: su mensaje raiz seguido de sus respuestas en orden de llegada
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal del mensaje
+ * @param id identificador del mensaje raiz, o de una de sus respuestas */
+func (server *Server) listThread(sender net.Addr, channelName string, id uint64) {
 
-				server.WriteResponse("LIST_MSG "+channelName, response)
-				client.WriteResponse(response)
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
 
-			}
+		channel := server.accessChannel(client, channelName)
+
+		if channel == nil { // Manejamos que el canal exista y el cliente tenga acceso
+			return
+		}
+
+		root := channel.findMessage(id)
+
+		if root != nil && root.parent != 0 { // Pidieron el hilo desde una respuesta
+			root = channel.findMessage(root.parent)
 		}
+
+		if root == nil { // Manejamos que el mensaje exista, los eliminados conservan su hilo
+			client.respondError(errors.New("no such message " + strconv.FormatUint(id, 10) + " in channel " + channelName))
+			return
+		}
+
+		// id,respuestas,fecha_mensaje,emisor,mensaje;id,respuestas,fecha_mensaje,emisor,mensaje
+		response := root.entry() + ";"
+
+		for _, reply := range root.replies {
+			response = response + reply.entry() + ";"
+		}
+
+		server.WriteResponse("THREAD "+channelName+" "+strconv.FormatUint(id, 10), response)
+		client.respond(response)
 	}
 }
 
@@ -255,9 +927,9 @@ func (server *Server) listMessages(sender net.Addr, channelName string) {
  * @param channelName nombre del canal que se listaran sus clientes */
 func (server *Server) listUsrChannel(sender net.Addr, channelName string) {
 
-	if client, ok := server.clients[sender]; ok { // Manejamos que el clientes que solicita exista en el servidor
+	if client, ok := server.client(sender); ok { // Manejamos que el clientes que solicita exista en el servidor
 
-		if channel, ok := server.channels[channelName]; ok { // Manejamos que el canal solicitado exista
+		if channel := server.accessChannel(client, channelName); channel != nil { // Manejamos que el canal exista y el cliente tenga acceso
 
 			if len(channel.clients) > 0 { // Verificamos si el canal tiene clientes antes de proceder
 
@@ -267,7 +939,7 @@ func (server *Server) listUsrChannel(sender net.Addr, channelName string) {
 				for key, values := range channel.clients {
 
 					if values { // Si el clientes esta conectado lo agregamos a los clientes
-						clients = append(clients, key.address.String())
+						clients = append(clients, key.name())
 					}
 				}
 
@@ -281,20 +953,283 @@ func (server *Server) listUsrChannel(sender net.Addr, channelName string) {
 				}
 
 				server.WriteResponse("LIST_USR "+channelName, response)
-				client.WriteResponse(response)
+				client.respond(response)
+
+			}
+		}
+	}
+}
+
+/* Funcion: topicChannel
+ * Consulta el tema de un canal, o lo cambia si se envia un tema nuevo. Solo los miembros pueden cambiarlo
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal
+ * @param topic tema nuevo, vacio para consultar el tema actual
+ * @param desc descripcion nueva, vacia para conservar la actual */
+func (server *Server) topicChannel(sender net.Addr, channelName string, topic []byte, desc []byte) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel := server.lookupChannel(client, channelName)
+
+		if channel == nil { // Manejamos que el canal exista
+			return
+		}
+
+		if len(topic) == 0 && !channel.isAccessibleTo(client) { // Manejamos que el cliente pertenezca a un canal con modos
+			client.respondError(errors.New("not a member of channel " + channelName))
+			return
+		}
+
+		if len(topic) == 0 { // Si no se envia un tema consultamos el actual: tema,descripcion
+			client.respond(escapeField(channel.topic) + "," + escapeField(channel.desc))
+			server.WriteResponse("TOPIC "+channelName, channel.topic)
+			return
+		}
+
+		if !channel.clients[client] && !channel.operators[client] { // Manejamos que el cliente pertenezca al canal
+			client.respondError(errors.New("not a member of channel " + channelName))
+			return
+		}
+
+		channel.topic = string(topic)
+
+		if len(desc) > 0 { // La descripcion solo se cambia si fue enviada
+			channel.desc = string(desc)
+		}
+		channel.touch()
+
+		channel.broadcast("TOPIC " + channel.name + " " + channel.topic) // Notificamos a los miembros del canal
+		server.channelEvent(EventChannelUpdated, channel)
+		server.WriteResponse("TOPIC "+channelName+" "+channel.topic, "CHANNEL TOPIC CHANGED")
+	}
+}
+
+/* Funcion: inviteClient
+ * Invita a un cliente a un canal, solo los operadores del canal pueden invitar
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal al que se invita
+ * @param target nombre del cliente invitado */
+func (server *Server) inviteClient(sender net.Addr, channelName string, target string) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel := server.lookupChannel(client, channelName)
+
+		if channel == nil { // Manejamos que el canal exista
+			return
+		}
+
+		if !channel.operators[client] { // Manejamos que el cliente sea operador del canal
+			client.respondError(errors.New("not an operator of channel " + channelName))
+			return
+		}
+
+		channel.invites[target] = true // Registramos la invitacion
+
+		if invited := server.findClient(target); invited != nil { // Si el invitado esta conectado le notificamos
+			invited.WriteResponse("INVITE " + channelName + " " + client.name())
+		}
+
+		server.WriteResponse("INVITE "+channelName+" "+target, "CLIENT INVITED")
+	}
+}
+
+/* Funcion: setMode
+ * Cambia un modo del canal, solo los operadores del canal pueden cambiarlos
+ * @param sender direccion del emisor de la solicitud.
+ * @param channelName nombre del canal a modificar
+ * @param mode modo a cambiar: +i/-i solo invitacion, +p/-p privado, +k/-k contraseña
+ * @param password contraseña del canal para el modo +k */
+func (server *Server) setMode(sender net.Addr, channelName string, mode string, password string) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		channel := server.lookupChannel(client, channelName)
+
+		if channel == nil { // Manejamos que el canal exista
+			return
+		}
+
+		if !channel.operators[client] { // Manejamos que el cliente sea operador del canal
+			client.respondError(errors.New("not an operator of channel " + channelName))
+			return
+		}
 
+		switch mode { // Segun sea el modo
+
+		case "+i", "-i": // Solo invitacion
+			channel.inviteOnly = mode == "+i"
+
+		case "+p", "-p": // Privado
+			channel.private = mode == "+p"
+
+		case "+k": // Contraseña
+			if password == "" {
+				client.respondError(errors.New("mode +k requires a password"))
+				return
+			}
+
+			hash, err := hashSecret(password)
+
+			if err != nil {
+				client.log().Error("hash password failed", "error", err)
+				client.respondError(errors.New("internal error setting channel password"))
+				return
 			}
+			channel.password = hash
+
+		case "-k":
+			channel.password = nil
+
+		default: // Si el modo no es reconocido
+			client.respondError(errors.New("unknown mode " + mode))
+			return
 		}
+
+		channel.broadcast("MODE " + channel.name + " " + channel.modes()) // Notificamos a los miembros del canal, el operador recibe su DONE
+		server.channelEvent(EventChannelUpdated, channel)
+		server.WriteResponse("MODE "+channelName+" "+mode, "CHANNEL MODE "+channel.modes())
+	}
+}
+
+/* Funcion: sendDirect
+ * Envia un mensaje directo entre nombres reclamados con contraseña. Si el destinatario esta desconectado se le
+ * entregara al volver a registrarse, hasta maxPendingDirects mensajes
+ * @param sender direccion del emisor de la solicitud.
+ * @param target nombre del cliente destinatario
+ * @param content mensaje a enviar
+ * @param file base64 de un archivo */
+func (server *Server) sendDirect(sender net.Addr, target string, content []byte, file []byte) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		if _, verified := client.account(); !verified { // Manejamos que el emisor sea el dueño de su nombre
+			client.respondError(errors.New("register a nickname with a password before sending direct messages"))
+			return
+		}
+
+		if _, ok := server.accounts[target]; !ok { // Manejamos que el destinatario haya reclamado su nombre
+			client.respondError(errors.New("no such user " + target))
+			return
+		}
+
+		recipient, online := server.nicknames[target] // Nadie mas puede registrar un nombre reclamado
+
+		if !online && len(server.pending[target]) >= maxPendingDirects { // Manejamos que el buzon del destinatario no este lleno
+			client.respondError(errors.New("too many pending direct messages for " + target))
+			return
+		}
+
+		key := conversationKey(client.nickname, target)
+		conversation, ok := server.directs[key]
+
+		if !ok { // Si es el primer mensaje entre ellos creamos la conversacion
+			conversation = NewConversation(client.nickname, target)
+			server.directs[key] = conversation
+		}
+
+		message := NewMessage(sender, client.nickname, content, file)
+		conversation.messages = append(conversation.messages, message)
+		conversation.date = message.date
+
+		if online { // Si el destinatario esta conectado se lo entregamos
+			recipient.WriteResponse("DM " + message.format())
+		} else { // Si no, lo guardamos para entregarlo cuando se vuelva a conectar
+			server.pending[target] = append(server.pending[target], message)
+		}
+
+		server.WriteResponse("DM "+client.nickname+" "+target, "DIRECT MESSAGE RECEIVED")
+	}
+}
+
+/* Funcion: listDirects
+ * Lista las conversaciones directas de un cliente, o los mensajes de una de ellas
+ * @param sender direccion del emisor de la solicitud.
+ * @param target nombre del otro participante, vacio para listar las conversaciones */
+func (server *Server) listDirects(sender net.Addr, target string) {
+
+	if client, ok := server.client(sender); ok { // Manejamos que el cliente que solicita exista en el servidor
+
+		if _, verified := client.account(); !verified { // Manejamos que el cliente sea el dueño de su nombre
+			client.respondError(errors.New("register a nickname with a password before listing direct messages"))
+			return
+		}
+
+		response := ""
+
+		if target == "" { // Listamos las conversaciones del cliente
+
+			conversations := make([]string, 0) // array de strings para ordenar las conversaciones por la fecha de su ultimo mensaje
+
+			for _, values := range server.directs {
+
+				if values.hasParticipant(client.nickname) {
+
+					//llenamos el array de conversaciones con: fecha_ultimo_mensaje,participante,cantidad_mensajes
+					conversations = append(conversations, values.date.Format("2006-01-02:15:04:05")+","+values.peer(client.nickname)+","+strconv.Itoa(len(values.messages)))
+				}
+			}
+
+			sort.Strings(conversations) //Ordenamos el array de conversaciones
+
+			for _, key := range conversations {
+				response = response + key + ";"
+			}
+
+		} else { // Listamos los mensajes de la conversacion con el cliente indicado
+
+			conversation, ok := server.directs[conversationKey(client.nickname, target)]
+
+			if !ok { // Manejamos que la conversacion exista
+				client.respondError(errors.New("no conversation with " + target))
+				return
+			}
+
+			for _, message := range conversation.messages { // Los mensajes ya estan en orden de llegada
+
+				// fecha_mensaje,emisor,mensaje,file;fecha_mensaje,emisor,mensaje,file
+				response = response + message.format() + ";"
+			}
+		}
+
+		server.WriteResponse("LIST_DM "+target, response)
+		client.respond(response)
 	}
 }
 
 /* Funcion
  * Nombre: WriteResponse
- * Descripcion: Escribe a la conexion del cliente la respuesta del servidor
+ * Descripcion: Publica para la consola una solicitud procesada y la respuesta del servidor
+ * @req: solicitud
  * @res: respuesta dada */
 func (server *Server) WriteResponse(req, res string) {
 
-	server.ReqAndRes = "Request: " + req + " | Response: " + res
-	//cmd := server.ReqAndRes
-	//log.Println(cmd)
+	server.SetResponse("Request: " + req + " | Response: " + res)
+}
+
+/* Funcion
+ * Nombre: SetResponse
+ * Descripcion: Publica un aviso para la consola, reemplazando el anterior si no se ha leido
+ * @text: aviso */
+func (server *Server) SetResponse(text string) {
+
+	server.reqAndResMu.Lock()
+	defer server.reqAndResMu.Unlock()
+
+	server.reqAndRes = text
+}
+
+/* Funcion
+ * Nombre: TakeResponse
+ * Descripcion: Retorna el ultimo aviso para la consola y lo descarta, asi cada aviso se envia una vez
+ * return: @string: aviso, vacio si no hay uno nuevo */
+func (server *Server) TakeResponse() string {
+
+	server.reqAndResMu.Lock()
+	defer server.reqAndResMu.Unlock()
+
+	text := server.reqAndRes
+	server.reqAndRes = ""
+	return text
 }
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* Funcion
+ * Nombre: TestStopReleasesClients
+ * Descripcion: Las conexiones que terminan despues de apagar el servidor no quedan esperando a que Run las
+ * desconecte */
+func TestStopReleasesClients(t *testing.T) {
+
+	server := NewServer()
+	server.Logger, _ = NewLogger(io.Discard, "error", FormatLogfmt)
+	server.ServerOn = true
+
+	stopped := make(chan struct{})
+	go func() {
+		server.Run()
+		close(stopped)
+	}()
+
+	connection, peer := net.Pipe()
+	client := NewClient(connection, server)
+	server.ClientOnlineReq <- client
+
+	exited := make(chan struct{})
+	go func() {
+		client.RequestReadHandle()
+		close(exited)
+	}()
+
+	server.Stop()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+
+	peer.Close() // El cliente se desconecta con el servidor apagado
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("client reader blocked disconnecting from a stopped server")
+	}
+}
+
+/* Funcion
+ * Nombre: TestStopReleasesRequests
+ * Descripcion: Las solicitudes que llegan con el servidor apagado, incluso antes de que registre al cliente,
+ * responden un error en lugar de quedar esperando */
+func TestStopReleasesRequests(t *testing.T) {
+
+	server := NewServer()
+	server.Logger, _ = NewLogger(io.Discard, "error", FormatLogfmt)
+
+	connection, peer := net.Pipe()
+	defer peer.Close()
+
+	client := NewClient(connection, server) // Run nunca lo registra
+	server.Stop()
+
+	go client.RequestReadHandle()
+	reader := bufio.NewReader(peer)
+
+	for i, request := range []string{"JOIN general", "LIST_CHN"} { // Comando de una particion y de Run
+
+		tag := strconv.Itoa(i + 1)
+		peer.SetDeadline(time.Now().Add(time.Second))
+
+		if _, err := peer.Write([]byte("@" + tag + " " + request + "\n")); err != nil {
+			t.Fatalf("%s: %v", request, err)
+		}
+
+		for _, want := range []string{"@" + tag + " ERROR server stopped", "DONE " + tag} {
+			line, err := reader.ReadString('\n')
+			if err != nil || strings.TrimRight(line, "\r\n") != want {
+				t.Fatalf("%s: got %q, %v, want %q", request, line, err, want)
+			}
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestTakeResponse
+ * Descripcion: Los avisos de la consola se escriben desde varias rutinas y cada uno se lee una sola vez */
+func TestTakeResponse(t *testing.T) {
+
+	server := NewServer()
+	done := make(chan struct{})
+
+	for i := 0; i < 4; i++ { // Como las particiones, que procesan sus comandos a la vez
+		go func() {
+			for j := 0; j < 100; j++ {
+				server.WriteResponse("MSG general", "MESSAGE RECEIVED")
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		server.TakeResponse()
+		<-done
+	}
+
+	server.SetResponse("Server is on")
+
+	if got := server.TakeResponse(); got != "Server is on" {
+		t.Fatalf("TakeResponse = %q, want %q", got, "Server is on")
+	}
+	if got := server.TakeResponse(); got != "" {
+		t.Fatalf("second TakeResponse = %q, want it empty", got)
+	}
+}
+
+/* Funcion
+ * Nombre: TestModeNotice
+ * Descripcion: Los cambios de modo se notifican a los miembros del canal, el operador solo recibe su DONE */
+func TestModeNotice(t *testing.T) {
+
+	server := NewServer()
+	server.Logger, _ = NewLogger(io.Discard, "error", FormatLogfmt)
+
+	connect := func(port int) *Client {
+		connection, peer := net.Pipe()
+		t.Cleanup(func() { peer.Close() })
+
+		client := NewClient(connection, server)
+		client.address = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port} // net.Pipe repite la direccion
+		server.clients[client.address] = client
+		return client
+	}
+
+	op, member := connect(5001), connect(5002)
+
+	channel := NewChannel("General", "op")
+	channel.operators[op] = true
+	channel.clients[member] = true
+	shardFor(server.shards, "General").channels[channelKey("General")] = channel
+
+	op.tag = "7" // Solicitud etiquetada del operador
+	server.setMode(op.address, "general", "+p", "")
+	server.setMode(op.address, "general", "+k", "clave")
+
+	for _, want := range []string{"MODE General +p", "MODE General +pk"} {
+		select {
+		case line := <-member.outbound:
+			if string(line) != want+"\n" {
+				t.Fatalf("member got %q, want %q", line, want)
+			}
+		default:
+			t.Fatalf("member without %q", want)
+		}
+	}
+
+	select {
+	case line := <-op.outbound: // No es miembro, y su respuesta es el DONE que envia quien procesa el comando
+		t.Fatalf("operator got %q", line)
+	default:
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Cantidad de particiones en las que se reparten los canales del servidor
+const ShardCount = 16
+
+// Estructura para una particion de los canales del servidor. Cada particion procesa en su propia rutina
+// los comandos de sus canales, asi el trafico de un canal no retrasa a los canales de otras particiones
+type shard struct {
+	mu       sync.RWMutex        // Protege los canales de la particion y su contenido
+	channels map[string]*Channel // Canales de la particion, por la llave normalizada de su nombre
+	commands chan Command        // Comandos de los canales de la particion
+}
+
+/* Funcion
+ * Nombre: newShard
+ * Descripcion: Funcion encargada de crear una particion apartir de la estructura */
+func newShard() *shard {
+
+	return &shard{
+		channels: make(map[string]*Channel),
+		commands: make(chan Command),
+	}
+}
+
+/* Funcion
+ * Nombre: shardFor
+ * Descripcion: Retorna la particion a la que pertenece un canal segun el hash de la llave de su nombre
+ * @shards: particiones del servidor
+ * @channelName: nombre del canal */
+func shardFor(shards []*shard, channelName string) *shard {
+
+	hash := fnv.New32a()
+	hash.Write([]byte(channelKey(channelName)))
+
+	return shards[hash.Sum32()%uint32(len(shards))]
+}
@@ -59,14 +59,36 @@ window.addEventListener("load", function (evt) {
 });
 */
 
+// Cantidad de muestras que se grafican, una por segundo
+const SAMPLES = 60;
+
+// Mensajes que se muestran del canal seleccionado
+const MESSAGES = 200;
+
+// Token de administracion: se abre el panel una vez con ?token=... o se pide, y se guarda en la sesion del navegador
+const TOKEN = (function () {
+    let token = new URLSearchParams(window.location.search).get("token") || sessionStorage.getItem("token");
+    if (!token) {
+        token = window.prompt("Admin token (SOCKETCAM_ADMINTOKEN)") || "";
+    }
+    sessionStorage.setItem("token", token);
+    return token;
+})();
+
+// Agrega el token a una ruta, el websocket y EventSource no permiten enviar la cabecera Authorization
+function withToken(path) {
+    return path + (path.indexOf("?") >= 0 ? "&" : "?") + "token=" + encodeURIComponent(TOKEN);
+}
+
 new Vue({
 
     // Elemento del html donde trabajar
     el: '#app',
 
-    // Al crearse ejecutamos la funcion para conectarnos con el WebSocket
+    // Al crearse nos conectamos con el WebSocket y con los eventos del servidor
     created() {
         this.connectToWebSocket();
+        this.connectToEvents();
         console.log("conectado")
     },
 
@@ -74,7 +96,28 @@ new Vue({
     data: {
 
         ws: null,
+        events: null,
+        live: false,
         reqAndRes: [],
+        status: {},
+        clients: {},
+        channels: {},
+        selected: '',
+        messages: [],
+        samples: [],
+        previous: null,
+        last: { commands: 0, messages: 0, received: 0, sent: 0 },
+    },
+
+    // Listas ordenadas para las tablas
+    computed: {
+        clientList() {
+            return Object.values(this.clients).sort((a, b) => a.conn - b.conn);
+        },
+
+        channelList() {
+            return Object.values(this.channels).sort((a, b) => a.name.localeCompare(b.name));
+        }
     },
 
     // Metodos
@@ -93,14 +136,14 @@ new Vue({
             }
             new_uri += "//" + loc.host;
             new_uri += loc.pathname + "ws";
-            this.ws = new WebSocket(new_uri);
+            this.ws = new WebSocket(withToken(new_uri));
             this.ws.onopen = function(evt) {
                 console.log("OPEN");
             }
 
-            this.ws.onclose = function(evt) {
+            this.ws.onclose = (evt) => {
                 console.log("close")
-                ws = null;
+                this.ws = null;
             }
 
             this.ws.onmessage = (evt) => {
@@ -108,7 +151,7 @@ new Vue({
                 let date = new Date();
                 let arrayResponses = evt.data.split(";;");
                 let div = document.getElementById('console');
-                for (i in arrayResponses) {
+                for (let i in arrayResponses) {
                     this.reqAndRes.push({
                         text: arrayResponses[i],
                         date: date.toLocaleDateString() + " " + date.toLocaleTimeString()
@@ -122,6 +165,182 @@ new Vue({
             return false;
         },
 
+        // Se conecta al flujo de eventos, el navegador reconecta solo y cada conexion empieza con el estado completo
+        connectToEvents() {
+            this.events = new EventSource(withToken("api/events"));
+
+            this.events.onopen = () => {
+                this.live = true;
+            }
+
+            this.events.onerror = () => {
+                this.live = false;
+            }
+
+            this.events.onmessage = (evt) => {
+                this.handle(JSON.parse(evt.data));
+            }
+        },
+
+        // Aplica un evento del servidor al estado del panel
+        handle(event) {
+
+            switch (event.type) {
+
+                case "snapshot":
+                    this.status = event.status;
+                    this.clients = {};
+                    this.channels = {};
+                    event.clients.forEach(c => this.$set(this.clients, c.conn, c));
+                    event.channels.forEach(c => this.$set(this.channels, c.name, c));
+                    this.previous = null;
+                    if (this.selected && !this.channels[this.selected]) {
+                        this.selected = '';
+                    }
+                    this.loadMessages();
+                    break;
+
+                case "client_connected":
+                case "client_registered":
+                    this.$set(this.clients, event.client.conn, event.client);
+                    break;
+
+                case "client_disconnected":
+                    this.$delete(this.clients, event.client.conn);
+                    break;
+
+                case "channel_created":
+                case "channel_updated":
+                    this.$set(this.channels, event.channel.name, event.channel);
+                    break;
+
+                case "channel_deleted":
+                    this.$delete(this.channels, event.channel.name);
+                    if (event.channel.name === this.selected) {
+                        this.selected = '';
+                        this.messages = [];
+                    }
+                    break;
+
+                case "message":
+                    this.$set(this.channels, event.channel.name, event.channel);
+                    if (event.channel.name === this.selected) {
+                        this.messages.push(event.message);
+                        this.messages.splice(0, this.messages.length - MESSAGES);
+                        this.scrollMessages();
+                    }
+                    break;
+
+                case "message_edited":
+                case "message_deleted":
+                case "message_reacted":
+                    this.$set(this.channels, event.channel.name, event.channel);
+                    if (event.channel.name === this.selected) {
+                        this.messages = this.messages.map(m => m.id === event.message.id ? event.message : m);
+                    }
+                    break;
+
+                case "stats":
+                    this.status = event.status;
+                    this.sample(event.status.totals, new Date(event.time));
+                    break;
+            }
+        },
+
+        // Selecciona un canal y carga sus ultimos mensajes
+        select(name) {
+            this.selected = name;
+            this.loadMessages();
+        },
+
+        loadMessages() {
+            if (!this.selected) {
+                this.messages = [];
+                return;
+            }
+
+            let name = this.selected;
+
+            fetch(withToken("api/channels/" + encodeURIComponent(name) + "/messages?limit=" + MESSAGES))
+                .then(response => response.ok ? response.json() : { messages: [] })
+                .then(page => {
+                    if (name === this.selected) { // Manejamos que no se haya cambiado de canal mientras cargaba
+                        this.messages = page.messages;
+                        this.scrollMessages();
+                    }
+                })
+                .catch(err => console.log("ERROR: " + err));
+        },
+
+        scrollMessages() {
+            this.$nextTick(() => {
+                let div = this.$refs.messages;
+                div.scrollTop = div.scrollHeight;
+            });
+        },
+
+        // Calcula el rendimiento con la diferencia entre dos muestras de los contadores acumulados
+        sample(totals, time) {
+
+            if (this.previous) {
+                let seconds = (time - this.previous.time) / 1000 || 1;
+                let rate = (key, scale) => Math.max(0, (totals[key] - this.previous.totals[key]) / seconds / scale);
+
+                this.last = {
+                    commands: Math.round(rate("commands", 1)),
+                    messages: Math.round(rate("messages", 1)),
+                    received: rate("receivedBytes", 1024).toFixed(1),
+                    sent: rate("sentBytes", 1024).toFixed(1),
+                };
+                this.samples.push(this.last);
+                this.samples.splice(0, this.samples.length - SAMPLES);
+                this.draw();
+            }
+            this.previous = { totals: totals, time: time };
+        },
+
+        draw() {
+            this.chart(this.$refs.rates, [
+                { key: "commands", color: "#99ff00" },
+                { key: "messages", color: "#33ccff" },
+            ]);
+            this.chart(this.$refs.bytes, [
+                { key: "received", color: "#ffcc00" },
+                { key: "sent", color: "#ff6699" },
+            ]);
+        },
+
+        // Dibuja las series de las muestras en un canvas, escaladas al maximo valor
+        chart(canvas, series) {
+
+            canvas.width = canvas.clientWidth;
+            canvas.height = canvas.clientHeight;
+
+            let ctx = canvas.getContext("2d");
+            let max = 1;
+
+            this.samples.forEach(s => series.forEach(serie => max = Math.max(max, Number(s[serie.key]))));
+
+            ctx.clearRect(0, 0, canvas.width, canvas.height);
+            ctx.fillStyle = "#888";
+            ctx.fillText(max.toFixed(0), 2, 10);
+
+            series.forEach(serie => {
+                ctx.strokeStyle = serie.color;
+                ctx.beginPath();
+                this.samples.forEach((s, i) => {
+                    let x = canvas.width * i / (SAMPLES - 1);
+                    let y = canvas.height - (canvas.height - 12) * Number(s[serie.key]) / max;
+                    i === 0 ? ctx.moveTo(x, y) : ctx.lineTo(x, y);
+                });
+                ctx.stroke();
+            });
+        },
+
+        formatTime(date) {
+            return new Date(date).toLocaleTimeString();
+        },
+
         on() {
 
             if (!this.ws) {
@@ -3,17 +3,28 @@ package tcpServer
 /* flag: bandera que sirve de utilidad para la linea de comandos
  * ftm: Imprementa Entradas / Salidas similares a C */
 import (
+	"errors"
 	"flag"
 	"log"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pipeduque/go-server/models"
 )
 
+// Espera antes de volver a aceptar conexiones despues de un error del oyente
+const acceptRetryDelay = 50 * time.Millisecond
+
 type TcpServer struct {
 	listener net.Listener
 	adrress  string
 	network  string
+	mu       sync.Mutex     // Protege el conteo de conexiones por IP
+	conns    map[string]int // Conexiones abiertas por cada IP
+	running  int32          // 1 mientras el oyente acepta conexiones
+	logger   *models.Logger // Registro del servidor TCP, se deriva del registro del servidor en Run
 }
 
 /* Funcion
@@ -45,6 +56,7 @@ func NewTcpServer() *TcpServer {
 		listener: listen,
 		adrress:  address,
 		network:  network,
+		conns:    make(map[string]int),
 	}
 }
 
@@ -53,33 +65,112 @@ func NewTcpServer() *TcpServer {
  * Descripcion: Corremos nuestro protocolo tcp, para recibir conexiones con clientes */
 func (tcpServer *TcpServer) Run(server *models.Server) {
 
+	atomic.StoreInt32(&tcpServer.running, 1)
+	defer atomic.StoreInt32(&tcpServer.running, 0)
 	defer tcpServer.listener.Close()
 
-	server.ReqAndRes = "Server started (" + tcpServer.network + ") " + tcpServer.adrress //Informamos la iniciacion del servidor
+	tcpServer.logger = server.Logger.With("component", "tcp")
+	tcpServer.logger.Info("tcp server started", "network", tcpServer.network, "address", tcpServer.adrress)
+
+	server.SetResponse("Server started (" + tcpServer.network + ") " + tcpServer.adrress) //Informamos la iniciacion del servidor
 
 	//Ciclo de conexion - Maneja las solicitudes entrantes
 	for server.ServerOn {
 		connection, err := tcpServer.listener.Accept() //Usamos el oyente con el punto aceptar para crear la conexion, que se bloqueará hasta que llegue una conexion con el cliente
 
-		if err != nil { //Manejamos un posible error al crear la conexion
-			log.Fatal(err)
-			if err := connection.Close(); err != nil {
-				log.Fatal("Failed to close listener:", err)
-			}
+		if errors.Is(err, net.ErrClosed) { // El oyente se cerro al apagar el servidor
+			tcpServer.logger.Info("tcp server stopped")
+			return
+		}
+
+		if err != nil { //Manejamos un posible error al crear la conexion, por ejemplo sin descriptores disponibles
+			tcpServer.logger.Error("accept failed", "error", err)
+			time.Sleep(acceptRetryDelay) // Esperamos antes de reintentar para no saturar el procesador
+			continue
+		}
+
+		if server.Banned(connection.RemoteAddr()) { // Rechazamos las conexiones de IPs baneadas temporalmente
+			tcpServer.reject(connection, "banned")
+			continue
+		}
+
+		ip := models.HostOf(connection.RemoteAddr())
+
+		if !tcpServer.acquire(ip, server.Config.MaxConnsPerIP) { // Rechazamos la conexion si la IP supera el limite de conexiones
+			tcpServer.reject(connection, "too many connections from "+ip)
 			continue
 		}
 
-		server.ReqAndRes = "Connected to " + connection.RemoteAddr().String()
+		server.SetResponse("Connected to " + connection.RemoteAddr().String())
 
 		client := models.NewClient(connection, server) // Referenciamos al nuevo cliente que creo la conexion
 		server.ClientOnlineReq <- client               // Lo conectamos al servidor
 
-		go client.RequestReadHandle() //LLamamos al manejador de lectura de solicitudes del cliente
+		go func() {
+			client.RequestReadHandle() //LLamamos al manejador de lectura de solicitudes del cliente
+			tcpServer.release(ip)      // Al terminar la conexion liberamos su cupo de la IP
+		}()
 	}
 
 }
 
+/* Funcion
+ * Nombre: acquire
+ * Descripcion: Reserva un cupo de conexion para una IP
+ * @ip: IP de la conexion
+ * @max: conexiones simultaneas permitidas por IP, cero para no limitar
+ * return: @bool: true si la IP tenia cupo */
+func (tcpServer *TcpServer) acquire(ip string, max int) bool {
+
+	tcpServer.mu.Lock()
+	defer tcpServer.mu.Unlock()
+
+	if max > 0 && tcpServer.conns[ip] >= max {
+		return false
+	}
+	tcpServer.conns[ip]++
+	return true
+}
+
+/* Funcion
+ * Nombre: release
+ * Descripcion: Libera el cupo de conexion de una IP
+ * @ip: IP de la conexion */
+func (tcpServer *TcpServer) release(ip string) {
+
+	tcpServer.mu.Lock()
+	defer tcpServer.mu.Unlock()
+
+	if tcpServer.conns[ip]--; tcpServer.conns[ip] <= 0 {
+		delete(tcpServer.conns, ip)
+	}
+}
+
+/* Funcion
+ * Nombre: reject
+ * Descripcion: Informa al cliente la razon por la que se rechaza su conexion y la cierra
+ * @connection: conexion rechazada
+ * @reason: razon del rechazo */
+func (tcpServer *TcpServer) reject(connection net.Conn, reason string) {
+
+	tcpServer.logger.Warn("connection rejected", "remote", connection.RemoteAddr(), "reason", reason)
+	connection.Write([]byte("ERROR " + reason + "\n"))
+
+	if err := connection.Close(); err != nil {
+		tcpServer.logger.Warn("close failed", "remote", connection.RemoteAddr(), "error", err)
+	}
+}
+
 func (tcpServer *TcpServer) StopTcp() {
 
+	atomic.StoreInt32(&tcpServer.running, 0)
 	tcpServer.listener.Close()
 }
+
+/* Funcion
+ * Nombre: Listening
+ * Descripcion: Indica si el oyente TCP esta aceptando conexiones */
+func (tcpServer *TcpServer) Listening() bool {
+
+	return atomic.LoadInt32(&tcpServer.running) == 1
+}
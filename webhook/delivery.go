@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Encabezados de las entregas
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp" // Segundos Unix del intento, forman parte de la firma
+	SignatureHeader = "X-Webhook-Signature" // sha256=[HMAC-SHA256 hexadecimal de timestamp.cuerpo con la clave]
+)
+
+// Antiguedad maxima de la marca de tiempo de una entrega que acepta Verify, asi una entrega capturada no se
+// puede repetir despues
+const Tolerance = 5 * time.Minute
+
+// Errores de la verificacion de las entregas
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Estructura para el cuerpo JSON de una entrega
+type Payload struct {
+	Delivery string              `json:"delivery"` // Identificador de la entrega, se repite en los reintentos
+	Event    string              `json:"event"`
+	Time     time.Time           `json:"time"`
+	Webhook  uint64              `json:"webhook"`
+	Channel  *models.ChannelInfo `json:"channel"`
+	Message  *models.MessageInfo `json:"message,omitempty"` // Eventos message
+	Client   *models.ClientInfo  `json:"client,omitempty"`  // Eventos join y leave
+}
+
+// Estructura para una entrega pendiente
+type delivery struct {
+	id    string
+	event string
+	body  []byte
+}
+
+// Estructura para una entrega que agoto sus intentos o no se pudo encolar
+type DeadLetter struct {
+	ID       uint64          `json:"id"`
+	Webhook  uint64          `json:"webhook"`
+	URL      string          `json:"url"`
+	Delivery string          `json:"delivery"`
+	Event    string          `json:"event"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Time     time.Time       `json:"time"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+/* Funcion
+ * Nombre: Sign
+ * Descripcion: Retorna la firma de una entrega con la clave de la suscripcion, como se envia en SignatureHeader.
+ * Se firma timestamp.cuerpo, y el cuerpo incluye el identificador de la entrega, asi ninguno de los dos se puede
+ * cambiar sin invalidar la firma
+ * @timestamp: valor de TimestampHeader */
+func Sign(secret string, timestamp string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/* Funcion
+ * Nombre: Verify
+ * Descripcion: Comprueba la firma de una entrega y que su marca de tiempo no tenga mas de Tolerance, para los
+ * receptores de los webhooks. Los reintentos llevan su propia marca de tiempo, el receptor los reconoce por el
+ * identificador de la entrega
+ * @timestamp: valor de TimestampHeader
+ * @signature: valor de SignatureHeader
+ * return: @error: ErrInvalidSignature o ErrStaleTimestamp si la entrega no se debe aceptar */
+func Verify(secret string, timestamp string, body []byte, signature string) error {
+
+	return verify(secret, timestamp, body, signature, time.Now())
+}
+
+/* Funcion
+ * Nombre: verify
+ * Descripcion: Comprueba una entrega como Verify en el momento dado */
+func verify(secret string, timestamp string, body []byte, signature string, now time.Time) error {
+
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+
+	if age := now.Sub(time.Unix(seconds, 0)); age > Tolerance || age < -Tolerance { // Tambien rechazamos las del futuro
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+/* Funcion
+ * Nombre: DeadLetters
+ * Descripcion: Retorna las entregas fallidas guardadas, de la mas antigua a la mas reciente */
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]DeadLetter{}, d.deadLetters...)
+}
+
+/* Funcion
+ * Nombre: enqueue
+ * Descripcion: Arma la entrega de un evento para una suscripcion, debe llamarse con el repartidor bloqueado. Si la
+ * cola esta llena la entrega pasa al registro de fallidas */
+func (d *Dispatcher) enqueue(h *hook, kind string, event models.Event) {
+
+	h.sent++
+
+	payload := Payload{
+		Delivery: strconv.FormatUint(h.ID, 10) + "-" + strconv.FormatUint(h.sent, 10),
+		Event:    kind,
+		Time:     event.Time,
+		Webhook:  h.ID,
+		Channel:  event.Channel,
+		Message:  event.Message,
+		Client:   event.Client,
+	}
+
+	body, _ := json.Marshal(payload)
+	next := delivery{id: payload.Delivery, event: kind, body: body}
+
+	select {
+	case h.queue <- next:
+	default: // El receptor no alcanza a recibir las entregas
+		d.deadLetter(h, next, 0, errors.New("delivery queue full"))
+	}
+}
+
+/* Funcion
+ * Nombre: deliver
+ * Descripcion: Envia en orden las entregas de una suscripcion hasta que se elimine */
+func (d *Dispatcher) deliver(h *hook) {
+
+	for {
+		select {
+
+		case <-h.stop:
+			return
+
+		case next := <-h.queue:
+			attempts, err := d.attempt(h, next)
+
+			if err != nil {
+				d.mu.Lock()
+				d.deadLetter(h, next, attempts, err)
+				d.mu.Unlock()
+			}
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: attempt
+ * Descripcion: Envia una entrega, reintentando con esperas crecientes mientras el error sea transitorio
+ * return: @int: intentos realizados
+ *         @error: ultimo error, nil si el receptor respondio 2xx */
+func (d *Dispatcher) attempt(h *hook, next delivery) (int, error) {
+
+	delay := d.options.RetryDelay
+
+	for attempt := 1; ; attempt++ {
+
+		retry, err := d.post(h, next)
+
+		if err == nil {
+			d.logger.Debug("webhook delivered", "webhook", h.ID, "delivery", next.id, "event", next.event, "attempts", attempt)
+			return attempt, nil
+		}
+
+		if !retry || attempt >= d.options.Attempts {
+			return attempt, err
+		}
+
+		d.logger.Debug("webhook attempt failed", "webhook", h.ID, "delivery", next.id, "attempt", attempt, "retry_in", delay, "error", err)
+
+		select {
+		case <-h.stop: // La suscripcion se elimino mientras esperabamos
+			return attempt, errors.New("webhook removed")
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > d.options.MaxRetryDelay {
+			delay = d.options.MaxRetryDelay
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: post
+ * Descripcion: Realiza un intento de una entrega
+ * return: @bool: true si el error es transitorio y se puede reintentar
+ *         @error: err si el receptor no respondio 2xx */
+func (d *Dispatcher) post(h *hook, next delivery) (bool, error) {
+
+	request, err := http.NewRequest("POST", h.URL, bytes.NewReader(next.body))
+	if err != nil {
+		return false, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "go-server-webhook")
+	request.Header.Set(EventHeader, next.event)
+	request.Header.Set(DeliveryHeader, next.id)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10) // Cada intento se firma con su momento
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(h.Secret, timestamp, next.body))
+
+	response, err := d.client.Do(request)
+	if err != nil { // Errores de red y vencimientos
+		return true, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = errors.New("unexpected status " + response.Status)
+
+	switch {
+	case response.StatusCode >= 500, response.StatusCode == http.StatusTooManyRequests, response.StatusCode == http.StatusRequestTimeout:
+		return true, err
+	}
+	return false, err // El receptor rechazo la entrega, reintentarla no cambia la respuesta
+}
+
+/* Funcion
+ * Nombre: deadLetter
+ * Descripcion: Guarda una entrega fallida, descartando las mas antiguas, debe llamarse con el repartidor bloqueado */
+func (d *Dispatcher) deadLetter(h *hook, next delivery, attempts int, err error) {
+
+	d.failures++
+
+	d.deadLetters = append(d.deadLetters, DeadLetter{
+		ID:       d.failures,
+		Webhook:  h.ID,
+		URL:      h.URL,
+		Delivery: next.id,
+		Event:    next.event,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Time:     time.Now(),
+		Payload:  next.body,
+	})
+
+	if len(d.deadLetters) > d.options.DeadLetters {
+		d.deadLetters = d.deadLetters[len(d.deadLetters)-d.options.DeadLetters:]
+	}
+
+	d.logger.Warn("webhook delivery failed", "webhook", h.ID, "delivery", next.id, "event", next.event, "attempts", attempts, "error", err)
+}
@@ -0,0 +1,319 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Eventos que se pueden suscribir
+const (
+	EventMessage        = "message"         // Mensaje en el canal
+	EventJoin           = "join"            // Un cliente entro al canal
+	EventLeave          = "leave"           // Un cliente salio del canal o se desconecto
+	EventChannelCreated = "channel_created" // Se creo el canal
+)
+
+// Canal de una suscripcion que recibe los eventos de todos los canales
+const AllChannels = "*"
+
+// Eventos del servidor que se guardan mientras se reparten a las suscripciones, los siguientes se pierden
+const eventBuffer = 1024
+
+// Entregas pendientes de cada suscripcion, las siguientes van al registro de fallidas
+const queueSize = 256
+
+// Errores de las suscripciones
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Eventos del servidor que corresponden a cada evento de las suscripciones
+var serverEvents = map[string]string{
+	models.EventMessage:        EventMessage,
+	models.EventMemberJoined:   EventJoin,
+	models.EventMemberLeft:     EventLeave,
+	models.EventChannelCreated: EventChannelCreated,
+}
+
+// Estructura con las opciones de las entregas, los valores en cero usan los valores por defecto
+type Options struct {
+	Attempts      int           // Intentos de cada entrega antes de pasarla al registro de fallidas
+	RetryDelay    time.Duration // Espera antes del primer reintento, se duplica en cada fallo
+	MaxRetryDelay time.Duration // Espera maxima entre reintentos
+	Timeout       time.Duration // Tiempo maximo de cada intento
+	DeadLetters   int           // Entregas fallidas que se guardan, se descartan las mas antiguas
+	AllowedHosts  []string      // Destinos permitidos, como host o host:puerto. Sin destinos no se aceptan suscripciones
+}
+
+// Estructura para una suscripcion de un canal
+type Subscription struct {
+	ID      uint64    `json:"id"`
+	Channel string    `json:"channel"` // Canal de los eventos, * para todos los canales
+	URL     string    `json:"url"`
+	Events  []string  `json:"events"`           // Eventos suscritos
+	Secret  string    `json:"secret,omitempty"` // Clave de la firma, solo se muestra al crear la suscripcion
+	Created time.Time `json:"created"`
+}
+
+// Estructura para una suscripcion activa con su cola de entregas
+type hook struct {
+	Subscription
+	queue chan delivery
+	stop  chan struct{} // Se cierra al eliminar la suscripcion
+	sent  uint64        // Entregas armadas, para el identificador de cada una
+}
+
+// Estructura para el repartidor de los eventos del servidor a las suscripciones
+type Dispatcher struct {
+	server  *models.Server
+	options Options
+	logger  *models.Logger
+	client  *http.Client
+
+	mu          sync.Mutex // Protege los campos siguientes
+	hooks       map[uint64]*hook
+	ids         uint64
+	unsubscribe func() // Cancela la suscripcion a los eventos del servidor, nil si no hay suscripciones
+	done        chan struct{}
+	deadLetters []DeadLetter
+	failures    uint64
+}
+
+/* Funcion
+ * Nombre: NewDispatcher
+ * Descripcion: Crea el repartidor de los webhooks de un servidor. Solo recibe los eventos del servidor mientras
+ * tenga suscripciones */
+func NewDispatcher(server *models.Server, options Options, logger *models.Logger) *Dispatcher {
+
+	if options.Attempts <= 0 {
+		options.Attempts = 5
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = time.Second
+	}
+	if options.MaxRetryDelay <= 0 {
+		options.MaxRetryDelay = time.Minute
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.DeadLetters <= 0 {
+		options.DeadLetters = 100
+	}
+
+	return &Dispatcher{
+		server:  server,
+		options: options,
+		logger:  logger.With("component", "webhook"),
+		client: &http.Client{
+			Timeout: options.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { // Una redireccion saldria de los destinos permitidos
+				return http.ErrUseLastResponse
+			},
+		},
+		hooks: make(map[uint64]*hook),
+	}
+}
+
+/* Funcion
+ * Nombre: Add
+ * Descripcion: Crea una suscripcion. Sin eventos se suscriben todos, y sin clave se genera una
+ * return: @Subscription: suscripcion creada, con su clave
+ *         @error: models.ErrInvalidArgument si algun campo no es valido */
+func (d *Dispatcher) Add(subscription Subscription) (Subscription, error) {
+
+	if err := validate(&subscription, d.options.AllowedHosts); err != nil {
+		return Subscription{}, err
+	}
+
+	if subscription.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return Subscription{}, err
+		}
+		subscription.Secret = hex.EncodeToString(secret)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ids++
+	subscription.ID = d.ids
+	subscription.Created = time.Now()
+
+	h := &hook{Subscription: subscription, queue: make(chan delivery, queueSize), stop: make(chan struct{})}
+	d.hooks[h.ID] = h
+	go d.deliver(h)
+
+	if d.unsubscribe == nil { // Primera suscripcion, empezamos a recibir los eventos del servidor
+		events, unsubscribe := d.server.Subscribe(eventBuffer)
+		d.unsubscribe, d.done = unsubscribe, make(chan struct{})
+		go d.listen(events, d.done)
+	}
+
+	d.logger.Info("webhook added", "webhook", h.ID, "channel", h.Channel, "url", h.URL, "events", strings.Join(h.Events, ","))
+	return subscription, nil
+}
+
+/* Funcion
+ * Nombre: Remove
+ * Descripcion: Elimina una suscripcion, descartando sus entregas pendientes
+ * return: @error: ErrWebhookNotFound si no existe */
+func (d *Dispatcher) Remove(id uint64) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok := d.hooks[id]
+
+	if !ok {
+		return ErrWebhookNotFound
+	}
+
+	delete(d.hooks, id)
+	close(h.stop)
+
+	if len(d.hooks) == 0 { // Ultima suscripcion, dejamos de recibir los eventos del servidor
+		d.unsubscribe()
+		close(d.done)
+		d.unsubscribe, d.done = nil, nil
+	}
+
+	d.logger.Info("webhook removed", "webhook", id)
+	return nil
+}
+
+/* Funcion
+ * Nombre: List
+ * Descripcion: Retorna las suscripciones ordenadas por su identificador, sin sus claves */
+func (d *Dispatcher) List() []Subscription {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0, len(d.hooks))
+
+	for _, h := range d.hooks {
+		subscription := h.Subscription
+		subscription.Secret = ""
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions
+}
+
+/* Funcion
+ * Nombre: listen
+ * Descripcion: Reparte los eventos del servidor a las colas de las suscripciones que los reciben
+ * @done: se cierra al cancelar la suscripcion a los eventos del servidor */
+func (d *Dispatcher) listen(events <-chan models.Event, done chan struct{}) {
+
+	for {
+		select {
+
+		case <-done:
+			return
+
+		case event := <-events:
+			kind, ok := serverEvents[event.Type]
+
+			if !ok || event.Channel == nil {
+				continue
+			}
+
+			d.mu.Lock()
+			for _, h := range d.hooks {
+				if h.receives(kind, event.Channel.Name) {
+					d.enqueue(h, kind, event)
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: receives
+ * Descripcion: Indica si la suscripcion recibe un evento de un canal, los nombres de los canales no distinguen
+ * mayusculas */
+func (h *hook) receives(kind string, channel string) bool {
+
+	if h.Channel != AllChannels && !strings.EqualFold(h.Channel, channel) {
+		return false
+	}
+
+	for _, event := range h.Events {
+		if event == kind {
+			return true
+		}
+	}
+	return false
+}
+
+/* Funcion
+ * Nombre: allowedHost
+ * Descripcion: Indica si la URL apunta a uno de los destinos permitidos. Un destino sin puerto permite
+ * cualquier puerto de ese host
+ * @target: URL del webhook
+ * @allowed: destinos permitidos */
+func allowedHost(target *url.URL, allowed []string) bool {
+
+	for _, host := range allowed {
+
+		host = strings.TrimSpace(host)
+
+		if strings.EqualFold(host, target.Host) || strings.EqualFold(host, target.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+/* Funcion
+ * Nombre: validate
+ * Descripcion: Valida y completa los campos de una nueva suscripcion. La URL debe apuntar a un destino permitido,
+ * asi una suscripcion no puede usar al servidor para llegar a servicios internos
+ * @subscription: suscripcion a validar
+ * @allowed: destinos permitidos, como host o host:puerto */
+func validate(subscription *Subscription, allowed []string) error {
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", models.ErrInvalidArgument, reason)
+	}
+
+	if subscription.Channel == "" {
+		return invalid("missing channel, use * for all channels")
+	}
+
+	target, err := url.Parse(subscription.URL)
+
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return invalid("invalid url " + fmt.Sprintf("%q", subscription.URL))
+	}
+
+	if !allowedHost(target, allowed) {
+		return invalid("host " + fmt.Sprintf("%q", target.Host) + " is not in the allowed webhook hosts")
+	}
+
+	if len(subscription.Events) == 0 { // Sin eventos se suscriben todos
+		subscription.Events = []string{EventMessage, EventJoin, EventLeave, EventChannelCreated}
+	}
+
+	for _, event := range subscription.Events {
+		switch event {
+		case EventMessage, EventJoin, EventLeave, EventChannelCreated:
+		default:
+			return invalid("unknown event " + fmt.Sprintf("%q", event))
+		}
+	}
+	return nil
+}
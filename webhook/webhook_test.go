@@ -0,0 +1,306 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pipeduque/go-server/models"
+)
+
+// Solicitud recibida por el receptor de prueba
+type received struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+// Receptor de prueba que responde los estados dados en orden, y 204 al agotarlos
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+	got      chan struct{}
+}
+
+/* Funcion
+ * Nombre: ServeHTTP
+ * Descripcion: Guarda la solicitud y responde el siguiente estado */
+func (r *receiver) ServeHTTP(writer http.ResponseWriter, reader *http.Request) {
+
+	body, _ := ioutil.ReadAll(reader.Body)
+
+	r.mu.Lock()
+	status := http.StatusNoContent
+	if len(r.requests) < len(r.statuses) {
+		status = r.statuses[len(r.requests)]
+	}
+	r.requests = append(r.requests, received{header: reader.Header.Clone(), body: body, at: time.Now()})
+	r.mu.Unlock()
+
+	writer.WriteHeader(status)
+	r.got <- struct{}{}
+}
+
+/* Funcion
+ * Nombre: newTestDispatcher
+ * Descripcion: Crea un repartidor con esperas cortas y una suscripcion a un receptor de prueba
+ * return: @*Dispatcher: repartidor
+ *         @*hook: suscripcion activa
+ *         @*receiver: receptor de las entregas */
+func newTestDispatcher(t *testing.T, attempts int, statuses ...int) (*Dispatcher, *hook, *receiver) {
+
+	r := &receiver{statuses: statuses, got: make(chan struct{}, 16)}
+	target := httptest.NewServer(r)
+	t.Cleanup(target.Close)
+
+	logger, _ := models.NewLogger(io.Discard, "error", models.FormatLogfmt)
+	host, _ := url.Parse(target.URL)
+
+	d := NewDispatcher(models.NewServer(), Options{
+		Attempts:      attempts,
+		RetryDelay:    20 * time.Millisecond,
+		MaxRetryDelay: 30 * time.Millisecond,
+		AllowedHosts:  []string{host.Host},
+	}, logger)
+
+	subscription, err := d.Add(Subscription{Channel: AllChannels, URL: target.URL, Secret: "clave"})
+	if err != nil {
+		t.Fatalf("Add = %v", err)
+	}
+	t.Cleanup(func() { d.Remove(subscription.ID) })
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d, d.hooks[subscription.ID], r
+}
+
+/* Funcion
+ * Nombre: send
+ * Descripcion: Encola un evento message para la suscripcion */
+func send(d *Dispatcher, h *hook) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.enqueue(h, EventMessage, models.Event{
+		Type:    models.EventMessage,
+		Time:    time.Now(),
+		Channel: &models.ChannelInfo{Name: "general"},
+		Message: &models.MessageInfo{Content: "hola"},
+	})
+}
+
+/* Funcion
+ * Nombre: wait
+ * Descripcion: Espera n solicitudes en el receptor */
+func (r *receiver) wait(t *testing.T, n int) {
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("receiver got %d requests, want %d", i, n)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: waitDeadLetters
+ * Descripcion: Espera que el registro de fallidas tenga n entregas */
+func waitDeadLetters(t *testing.T, d *Dispatcher, n int) []DeadLetter {
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if letters := d.DeadLetters(); len(letters) >= n {
+			return letters
+		}
+	}
+	t.Fatalf("dead letters = %d, want %d", len(d.DeadLetters()), n)
+	return nil
+}
+
+/* Funcion
+ * Nombre: TestVerify
+ * Descripcion: La firma cubre la marca de tiempo y el cuerpo, y las marcas fuera de la tolerancia se rechazan */
+func TestVerify(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"delivery":"1-1","event":"message"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign("clave", timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		signature string
+		now       time.Time
+		err       error
+	}{
+		{"valid", "clave", timestamp, string(body), signature, now, nil},
+		{"near tolerance", "clave", timestamp, string(body), signature, now.Add(Tolerance), nil},
+		{"other secret", "otra", timestamp, string(body), signature, now, ErrInvalidSignature},
+		{"tampered body", "clave", timestamp, strings.Replace(string(body), "1-1", "1-2", 1), signature, now, ErrInvalidSignature},
+		{"tampered timestamp", "clave", strconv.FormatInt(now.Unix()+60, 10), string(body), signature, now, ErrInvalidSignature},
+		{"missing signature", "clave", timestamp, string(body), "", now, ErrInvalidSignature},
+		{"stale", "clave", timestamp, string(body), signature, now.Add(Tolerance + time.Second), ErrStaleTimestamp},
+		{"future", "clave", timestamp, string(body), signature, now.Add(-Tolerance - time.Second), ErrStaleTimestamp},
+		{"invalid timestamp", "clave", "ayer", string(body), Sign("clave", "ayer", body), now, ErrStaleTimestamp},
+	}
+
+	for _, test := range tests {
+		if err := verify(test.secret, test.timestamp, []byte(test.body), test.signature, test.now); !errors.Is(err, test.err) {
+			t.Errorf("%s: verify = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+/* Funcion
+ * Nombre: TestDeliverySigned
+ * Descripcion: Cada entrega lleva sus encabezados y una firma que el receptor puede verificar */
+func TestDeliverySigned(t *testing.T) {
+
+	d, h, r := newTestDispatcher(t, 1)
+
+	send(d, h)
+	r.wait(t, 1)
+
+	request := r.requests[0]
+	header := request.header
+
+	if header.Get(EventHeader) != EventMessage || header.Get(DeliveryHeader) != "1-1" {
+		t.Fatalf("headers event=%q delivery=%q", header.Get(EventHeader), header.Get(DeliveryHeader))
+	}
+	if err := Verify("clave", header.Get(TimestampHeader), request.body, header.Get(SignatureHeader)); err != nil {
+		t.Fatalf("Verify = %v", err)
+	}
+	if !strings.Contains(string(request.body), `"delivery":"1-1"`) {
+		t.Fatalf("body %s does not include the delivery id", request.body)
+	}
+}
+
+/* Funcion
+ * Nombre: TestDeliveryRetry
+ * Descripcion: Los errores transitorios se reintentan con esperas crecientes y la misma entrega */
+func TestDeliveryRetry(t *testing.T) {
+
+	d, h, r := newTestDispatcher(t, 5, http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+	send(d, h)
+	r.wait(t, 4)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.requests) != 4 {
+		t.Fatalf("receiver got %d requests, want 4", len(r.requests))
+	}
+
+	// Esperas de 20ms, 30ms y 30ms: se duplican sin pasar de MaxRetryDelay
+	for i, want := range []time.Duration{20, 30, 30} {
+		want *= time.Millisecond
+		if gap := r.requests[i+1].at.Sub(r.requests[i].at); gap < want || gap > want+time.Second {
+			t.Errorf("retry %d after %v, want %v", i+1, gap, want)
+		}
+	}
+
+	for i, request := range r.requests {
+		if id := request.header.Get(DeliveryHeader); id != "1-1" {
+			t.Errorf("attempt %d delivery = %q, want 1-1", i+1, id)
+		}
+		if err := Verify("clave", request.header.Get(TimestampHeader), request.body, request.header.Get(SignatureHeader)); err != nil {
+			t.Errorf("attempt %d Verify = %v", i+1, err)
+		}
+	}
+
+	if letters := d.DeadLetters(); len(letters) != 0 {
+		t.Fatalf("dead letters = %v after a delivery that succeeded", letters)
+	}
+}
+
+/* Funcion
+ * Nombre: TestDeliveryDeadLetter
+ * Descripcion: Las entregas que agotan sus intentos o que el receptor rechaza pasan al registro de fallidas */
+func TestDeliveryDeadLetter(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int // Intentos esperados
+		err      string
+	}{
+		{"exhausted", []int{500, 502, 503}, 3, "unexpected status 503 Service Unavailable"},
+		{"rejected", []int{400}, 1, "unexpected status 400 Bad Request"}, // Un 4xx no se reintenta
+		{"gone", []int{410}, 1, "unexpected status 410 Gone"},
+	}
+
+	for _, test := range tests {
+
+		d, h, r := newTestDispatcher(t, 3, test.statuses...)
+
+		send(d, h)
+		r.wait(t, test.attempts)
+		letters := waitDeadLetters(t, d, 1)
+
+		letter := letters[0]
+		if letter.Attempts != test.attempts || letter.Error != test.err || letter.Delivery != "1-1" || letter.Event != EventMessage {
+			t.Errorf("%s: dead letter = %+v", test.name, letter)
+		}
+		if !strings.Contains(string(letter.Payload), `"content":"hola"`) {
+			t.Errorf("%s: dead letter payload %s", test.name, letter.Payload)
+		}
+
+		r.mu.Lock()
+		if len(r.requests) != test.attempts {
+			t.Errorf("%s: receiver got %d requests, want %d", test.name, len(r.requests), test.attempts)
+		}
+		r.mu.Unlock()
+	}
+}
+
+/* Funcion
+ * Nombre: TestAddAllowedHosts
+ * Descripcion: Solo se aceptan suscripciones a los destinos permitidos */
+func TestAddAllowedHosts(t *testing.T) {
+
+	logger, _ := models.NewLogger(io.Discard, "error", models.FormatLogfmt)
+	d := NewDispatcher(models.NewServer(), Options{AllowedHosts: []string{"hooks.example.com", "localhost:9090"}}, logger)
+
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://hooks.example.com/chat", true},
+		{"http://HOOKS.example.com:8443/chat", true}, // Sin puerto en el destino se permite cualquiera
+		{"http://localhost:9090", true},
+		{"http://localhost:9091", false},
+		{"http://127.0.0.1:9090", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"ftp://hooks.example.com", false},
+	}
+
+	for _, test := range tests {
+
+		subscription, err := d.Add(Subscription{Channel: "general", URL: test.url})
+
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("Add(%q) = %v", test.url, err)
+		case !test.valid && !errors.Is(err, models.ErrInvalidArgument):
+			t.Errorf("Add(%q) = %v, want ErrInvalidArgument", test.url, err)
+		}
+
+		if err == nil {
+			d.Remove(subscription.ID)
+		}
+	}
+}